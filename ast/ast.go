@@ -11,16 +11,20 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/adamwoolhether/monkeyLang/token"
 )
 
 // Node defines the ontract for all nodes in the Monkey AST.
-// TokenLiteral is used for debugging and testing.
+// TokenLiteral is used for debugging and testing. Pos and End
+// report the span of source code the node was parsed from.
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the node's first character
+	End() token.Position // position immediately after the node's last character
 }
 
 // Statement nodes to not produce a value. ex:
@@ -44,6 +48,7 @@ type Expression interface {
 // series of statements.
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment // in source order, only collected if the lexer emits them
 }
 
 func (p *Program) TokenLiteral() string {
@@ -54,6 +59,22 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
@@ -64,16 +85,43 @@ func (p *Program) String() string {
 	return out.String()
 }
 
-// LetStatement represents a let statement in Monkey.
+// Comment represents a // line comment or a /* */ block comment.
+// Comments aren't part of the tree; the parser keeps them aside in
+// Program.Comments for tools that rewrite source.
+type Comment struct {
+	Token token.Token // the token.COMMENT token
+}
+
+func (c *Comment) TokenLiteral() string { return c.Token.Literal }
+func (c *Comment) String() string       { return c.Token.Literal }
+func (c *Comment) Pos() token.Position  { return c.Token.Pos }
+func (c *Comment) End() token.Position  { return c.Token.End }
+
+// LetStatement represents a let statement in Monkey, or a
+// const statement, whose binding can't be assigned to later.
 // It's methods satisfy the Statement and Node interfaces.
 type LetStatement struct {
-	Token token.Token // the token.LET token
+	Token token.Token // the token.LET or token.CONST token
 	Name  *Identifier
 	Value Expression
 }
 
+// IsConst reports whether the statement binds a constant.
+func (ls *LetStatement) IsConst() bool { return ls.Token.Type == token.CONST }
+
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+
+	return ls.Token.End
+}
 
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
@@ -91,6 +139,107 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// DestructuringLetStatement binds the names in a pattern to the parts
+// of an array or hash, ex: let [a, b] = xs; or let {name, age} = person;
+type DestructuringLetStatement struct {
+	Token   token.Token // the token.LET or token.CONST token
+	Pattern Pattern
+	Value   Expression
+}
+
+// IsConst reports whether the statement binds constants.
+func (ds *DestructuringLetStatement) IsConst() bool { return ds.Token.Type == token.CONST }
+
+func (ds *DestructuringLetStatement) statementNode()       {}
+func (ds *DestructuringLetStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DestructuringLetStatement) Pos() token.Position  { return ds.Token.Pos }
+func (ds *DestructuringLetStatement) End() token.Position {
+	if ds.Value != nil {
+		return ds.Value.End()
+	}
+
+	return ds.Pattern.End()
+}
+func (ds *DestructuringLetStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ds.TokenLiteral() + " ")
+	out.WriteString(ds.Pattern.String())
+	out.WriteString(" = ")
+
+	if ds.Value != nil {
+		out.WriteString(ds.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// Pattern is the left-hand side of a destructuring let statement.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// ArrayPattern matches the elements of an array by position. Rest,
+// if not nil, is bound to an array of the elements left over.
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []*Identifier
+	Rest     *Identifier
+	Rbracket token.Token // the closing ']' token
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) Pos() token.Position  { return ap.Token.Pos }
+func (ap *ArrayPattern) End() token.Position  { return ap.Rbracket.End }
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, el := range ap.Elements {
+		names = append(names, el.String())
+	}
+	if ap.Rest != nil {
+		names = append(names, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashPattern matches the values of a hash by the string keys
+// spelled out by its names, ex: {name} binds name to hash["name"].
+type HashPattern struct {
+	Token  token.Token // the '{' token
+	Keys   []*Identifier
+	Rbrace token.Token // the closing '}' token
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) Pos() token.Position  { return hp.Token.Pos }
+func (hp *HashPattern) End() token.Position  { return hp.Rbrace.End }
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+
+	keys := []string{}
+	for _, key := range hp.Keys {
+		keys = append(keys, key.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(keys, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
 // Identifier represents the identifiers of a binding.
 // Its methods satisfy the Expression interface.
 type Identifier struct {
@@ -101,6 +250,8 @@ type Identifier struct {
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
+func (i *Identifier) End() token.Position  { return i.Token.End }
 
 // ReturnStatement represents a return statement in Monkey.
 // It's methods satisfy the Statement and Node interfaces.
@@ -113,6 +264,14 @@ func (rs *ReturnStatement) statementNode() {}
 func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+
+	return rs.Token.End
+}
 
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -127,6 +286,164 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// WhileStatement represents a loop that runs its body
+// for as long as its condition is truthy.
+// while (<condition>) <body>
+type WhileStatement struct {
+	Token     token.Token // the 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) End() token.Position {
+	if ws.Body != nil {
+		return ws.Body.End()
+	}
+
+	return ws.Token.End
+}
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// ForInStatement represents a loop over the elements of an
+// iterable object, binding each one to Value and, optionally,
+// its index or hash key to Key.
+// for (<key>, <value> in <iterable>) <body>
+type ForInStatement struct {
+	Token    token.Token // the 'for' token
+	Key      *Identifier // nil if only the value is bound
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode()       {}
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForInStatement) End() token.Position {
+	if fs.Body != nil {
+		return fs.Body.End()
+	}
+
+	return fs.Token.End
+}
+func (fs *ForInStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for(")
+	if fs.Key != nil {
+		out.WriteString(fs.Key.String() + ", ")
+	}
+	out.WriteString(fs.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// BreakStatement exits the innermost enclosing loop.
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) End() token.Position  { return bs.Token.End }
+func (bs *BreakStatement) String() string       { return bs.Token.Literal + ";" }
+
+// ContinueStatement skips to the next iteration
+// of the innermost enclosing loop.
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) End() token.Position  { return cs.Token.End }
+func (cs *ContinueStatement) String() string       { return cs.Token.Literal + ";" }
+
+// TryStatement runs Block, handing any error raised within it to
+// the Catch block, with the error bound to Param. Finally, if set,
+// runs afterwards however the other blocks were left. At least one
+// of Catch and Finally is set.
+// try <block> catch (<param>) <catch> finally <finally>
+type TryStatement struct {
+	Token   token.Token // the 'try' token
+	Block   *BlockStatement
+	Param   *Identifier // nil if there's no catch block
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *TryStatement) End() token.Position {
+	switch {
+	case ts.Finally != nil:
+		return ts.Finally.End()
+	case ts.Catch != nil:
+		return ts.Catch.End()
+	case ts.Block != nil:
+		return ts.Block.End()
+	}
+
+	return ts.Token.End
+}
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(ts.Block.String())
+	if ts.Catch != nil {
+		out.WriteString(" catch (" + ts.Param.String() + ") ")
+		out.WriteString(ts.Catch.String())
+	}
+	if ts.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(ts.Finally.String())
+	}
+
+	return out.String()
+}
+
+// ThrowStatement raises Value as an error, to be caught by
+// the innermost enclosing try statement.
+// throw <value>;
+type ThrowStatement struct {
+	Token token.Token // the 'throw' token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *ThrowStatement) End() token.Position {
+	if ts.Value != nil {
+		return ts.Value.End()
+	}
+
+	return ts.Token.End
+}
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
 // ExpressionStatement represents an expression in Monkeu.
 // Its methods satisfy the Statement and Node interface.
 type ExpressionStatement struct {
@@ -136,6 +453,20 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position {
+	if es.Expression != nil {
+		return es.Expression.Pos()
+	}
+
+	return es.Token.Pos
+}
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+
+	return es.Token.End
+}
 
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
@@ -158,6 +489,21 @@ type IntegerLiteral struct {
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position  { return il.Token.End }
+
+// FloatLiteral represents floating-point numbers in Monkey,
+// ex: 1.5 or 1e-3. Value holds the parsed Token.Literal.
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position  { return fl.Token.End }
 
 // PrefixExpression represents a prefix expression. Operators in Monkey
 // are either a '-' or '!', and Right is the expression immediately
@@ -172,6 +518,14 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+
+	return pe.Token.End
+}
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -194,6 +548,20 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+
+	return ie.Token.Pos
+}
+func (ie *InfixExpression) End() token.Position {
+	if ie.Right != nil {
+		return ie.Right.End()
+	}
+
+	return ie.Token.End
+}
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -206,6 +574,74 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// ParenExpression represents an expression wrapped in parentheses,
+// ex: (1 + 2). It only records the parentheses, so that the span of
+// the nodes containing it matches their source, and prints like the
+// expression it wraps, as String already parenthesizes operations.
+type ParenExpression struct {
+	Token      token.Token // the '(' token
+	Expression Expression
+	Rparen     token.Token // the closing ')' token
+}
+
+func (pe *ParenExpression) expressionNode()      {}
+func (pe *ParenExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *ParenExpression) Pos() token.Position  { return pe.Token.Pos }
+func (pe *ParenExpression) End() token.Position  { return pe.Rparen.End }
+func (pe *ParenExpression) String() string       { return pe.Expression.String() }
+
+// Unparen returns e with any parentheses around it removed.
+func Unparen(e Expression) Expression {
+	for {
+		pe, ok := e.(*ParenExpression)
+		if !ok {
+			return e
+		}
+		e = pe.Expression
+	}
+}
+
+// IsCallOf reports whether node is a call of the identifier name.
+func IsCallOf(node Node, name string) bool {
+	call, ok := node.(*CallExpression)
+	if !ok {
+		return false
+	}
+
+	ident, ok := call.Function.(*Identifier)
+	return ok && ident.Value == name
+}
+
+// AssignExpression represents assigning a new value to an
+// existing binding, optionally combined with an infix operator.
+// <target> <operator> <value>, ex: x = 5 or x += 1
+type AssignExpression struct {
+	Token    token.Token // the assignment operator token, ex: '+='
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position  { return ae.Target.Pos() }
+func (ae *AssignExpression) End() token.Position {
+	if ae.Value != nil {
+		return ae.Value.End()
+	}
+
+	return ae.Token.End
+}
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+
+	return out.String()
+}
+
 // Boolean represents a Boolean expression.
 type Boolean struct {
 	Token token.Token
@@ -215,6 +651,8 @@ type Boolean struct {
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
+func (b *Boolean) End() token.Position  { return b.Token.End }
 
 // IfExpression represents an if-else-conditional, which are
 // expressions in Monkey. They produce a value. Block
@@ -229,6 +667,17 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+
+	return ie.Token.End
+}
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -247,12 +696,15 @@ func (ie *IfExpression) String() string {
 
 // BlockStatement represent statements in conditional expressions.
 type BlockStatement struct {
-	Token      token.Token
+	Token      token.Token // the '{' token
 	Statements []Statement
+	Rbrace     token.Token // the closing '}' token
 }
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position  { return bs.Rbrace.End }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -269,18 +721,35 @@ func (bs *BlockStatement) String() string {
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	Defaults   map[string]Expression // The default values of the parameters that have one.
+	Rest       *Identifier           // Takes any extra arguments, nil unless the func is variadic.
 	Body       *BlockStatement
 	Name       string
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+
+	return fl.Token.End
+}
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	params := []string{}
 	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+		if def, ok := fl.Defaults[p.Value]; ok {
+			params = append(params, p.String()+" = "+def.String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
 	}
 
 	out.WriteString(fl.TokenLiteral())
@@ -295,16 +764,75 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// MacroLiteral represents a macro, which is called with the
+// unevaluated AST of its arguments, and returns quoted AST that
+// replaces the call before the program runs.
+// macro <parameters> <block statement>
+type MacroLiteral struct {
+	Token      token.Token // the 'macro' token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) Pos() token.Position  { return ml.Token.Pos }
+func (ml *MacroLiteral) End() token.Position {
+	if ml.Body != nil {
+		return ml.Body.End()
+	}
+
+	return ml.Token.End
+}
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(ml.Body.String())
+
+	return out.String()
+}
+
+// SpreadExpression passes the elements of an array
+// as separate arguments to a call, ex: f(...args).
+type SpreadExpression struct {
+	Token token.Token // the '...' token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) Pos() token.Position  { return se.Token.Pos }
+func (se *SpreadExpression) End() token.Position {
+	if se.Value != nil {
+		return se.Value.End()
+	}
+
+	return se.Token.End
+}
+func (se *SpreadExpression) String() string { return "..." + se.Value.String() }
+
 // CallExpression represents a call to a function literal in Monkey.
 // <expression>(<comma separated expressions>)
 type CallExpression struct {
-	Token     token.Token
+	Token     token.Token // the '(' token
 	Function  Expression
 	Arguments []Expression
+	Rparen    token.Token // the closing ')' token
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Function.Pos() }
+func (ce *CallExpression) End() token.Position  { return ce.Rparen.End }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -330,15 +858,20 @@ type StringLiteral struct {
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position  { return sl.Token.End }
 
 // ArrayLiteral represents an array in Monkey.
 type ArrayLiteral struct {
-	Token    token.Token
+	Token    token.Token // the '[' token
 	Elements []Expression
+	Rbracket token.Token // the closing ']' token
 }
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position  { return al.Rbracket.End }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -357,13 +890,16 @@ func (al *ArrayLiteral) String() string {
 // IndexExpression allows support to access a value of an
 // array's index position.
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token // the '[' token
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token // the closing ']' token
 }
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Left.Pos() }
+func (ie *IndexExpression) End() token.Position  { return ie.Rbracket.End }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -379,12 +915,37 @@ func (ie *IndexExpression) String() string {
 // HashLiteral represents comma-separated list of key-value pairs.
 // it is represented by Gp's map structure.
 type HashLiteral struct {
-	Token token.Token // The '{' token
-	Pairs map[Expression]Expression
+	Token  token.Token // The '{' token
+	Pairs  map[Expression]Expression
+	Rbrace token.Token // the closing '}' token
 }
 
 func (hl HashLiteral) expressionNode()      {}
 func (hl HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl HashLiteral) Pos() token.Position  { return hl.Token.Pos }
+func (hl HashLiteral) End() token.Position  { return hl.Rbrace.End }
+
+// Keys returns the keys of the hash's pairs in the order they were
+// written in, as Go maps have no order of their own. Keys built by
+// hand rather than parsed share the zero position, so they fall back
+// on the order of their text.
+func (hl HashLiteral) Keys() []Expression {
+	keys := make([]Expression, 0, len(hl.Pairs))
+	for key := range hl.Pairs {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		oi, oj := keys[i].Pos().Offset, keys[j].Pos().Offset
+		if oi != oj {
+			return oi < oj
+		}
+		return keys[i].String() < keys[j].String()
+	})
+
+	return keys
+}
+
 func (hl HashLiteral) String() string {
 	var out bytes.Buffer
 
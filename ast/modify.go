@@ -0,0 +1,231 @@
+package ast
+
+// ModifierFunc returns the node to replace the given one with.
+type ModifierFunc func(Node) Node
+
+// Modify returns a copy of the AST rooted at node in which every node
+// has been replaced by the result of passing it to modifier, children
+// before their parents and in the order Walk visits them. The original
+// AST is left untouched, though nodes without children are shared with
+// it. A modifier returning a node of a kind that can't take the place
+// of the one it was given leaves a nil in its place.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		n := *node
+		n.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&n)
+
+	case *ExpressionStatement:
+		n := *node
+		n.Expression = modifyExpression(node.Expression, modifier)
+		return modifier(&n)
+
+	case *BlockStatement:
+		n := *node
+		n.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&n)
+
+	case *LetStatement:
+		n := *node
+		n.Name = modifyIdentifier(node.Name, modifier)
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *DestructuringLetStatement:
+		n := *node
+		if node.Pattern != nil {
+			n.Pattern, _ = Modify(node.Pattern, modifier).(Pattern)
+		}
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *ArrayPattern:
+		n := *node
+		n.Elements = modifyIdentifiers(node.Elements, modifier)
+		n.Rest = modifyIdentifier(node.Rest, modifier)
+		return modifier(&n)
+
+	case *HashPattern:
+		n := *node
+		n.Keys = modifyIdentifiers(node.Keys, modifier)
+		return modifier(&n)
+
+	case *ReturnStatement:
+		n := *node
+		n.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+		return modifier(&n)
+
+	case *WhileStatement:
+		n := *node
+		n.Condition = modifyExpression(node.Condition, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *ForInStatement:
+		n := *node
+		n.Key = modifyIdentifier(node.Key, modifier)
+		n.Value = modifyIdentifier(node.Value, modifier)
+		n.Iterable = modifyExpression(node.Iterable, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *TryStatement:
+		n := *node
+		n.Block = modifyBlock(node.Block, modifier)
+		n.Param = modifyIdentifier(node.Param, modifier)
+		n.Catch = modifyBlock(node.Catch, modifier)
+		n.Finally = modifyBlock(node.Finally, modifier)
+		return modifier(&n)
+
+	case *ThrowStatement:
+		n := *node
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *ParenExpression:
+		n := *node
+		n.Expression = modifyExpression(node.Expression, modifier)
+		return modifier(&n)
+
+	case *PrefixExpression:
+		n := *node
+		n.Right = modifyExpression(node.Right, modifier)
+		return modifier(&n)
+
+	case *InfixExpression:
+		n := *node
+		n.Left = modifyExpression(node.Left, modifier)
+		n.Right = modifyExpression(node.Right, modifier)
+		return modifier(&n)
+
+	case *AssignExpression:
+		n := *node
+		n.Target = modifyExpression(node.Target, modifier)
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *IfExpression:
+		n := *node
+		n.Condition = modifyExpression(node.Condition, modifier)
+		n.Consequence = modifyBlock(node.Consequence, modifier)
+		n.Alternative = modifyBlock(node.Alternative, modifier)
+		return modifier(&n)
+
+	case *IndexExpression:
+		n := *node
+		n.Left = modifyExpression(node.Left, modifier)
+		n.Index = modifyExpression(node.Index, modifier)
+		return modifier(&n)
+
+	case *FunctionLiteral:
+		n := *node
+		n.Parameters = make([]*Identifier, len(node.Parameters))
+		if node.Defaults != nil {
+			n.Defaults = make(map[string]Expression, len(node.Defaults))
+		}
+		// A default is keyed by the name of its parameter, which the
+		// modifier may rename, and goes if the parameter is removed.
+		for i, param := range node.Parameters {
+			n.Parameters[i] = modifyIdentifier(param, modifier)
+			if param == nil || n.Parameters[i] == nil {
+				continue
+			}
+			if def, ok := node.Defaults[param.Value]; ok {
+				n.Defaults[n.Parameters[i].Value] = modifyExpression(def, modifier)
+			}
+		}
+		n.Rest = modifyIdentifier(node.Rest, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *MacroLiteral:
+		n := *node
+		n.Parameters = modifyIdentifiers(node.Parameters, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *CallExpression:
+		n := *node
+		n.Function = modifyExpression(node.Function, modifier)
+		n.Arguments = modifyExpressions(node.Arguments, modifier)
+		return modifier(&n)
+
+	case *SpreadExpression:
+		n := *node
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *ArrayLiteral:
+		n := *node
+		n.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&n)
+
+	case *HashLiteral:
+		n := *node
+		n.Pairs = make(map[Expression]Expression, len(node.Pairs))
+		for _, key := range node.Keys() {
+			n.Pairs[modifyExpression(key, modifier)] = modifyExpression(node.Pairs[key], modifier)
+		}
+		return modifier(&n)
+	}
+
+	return modifier(node)
+}
+
+func modifyStatements(stmts []Statement, modifier ModifierFunc) []Statement {
+	modified := make([]Statement, len(stmts))
+	for i, s := range stmts {
+		modified[i], _ = Modify(s, modifier).(Statement)
+	}
+
+	return modified
+}
+
+func modifyExpressions(exps []Expression, modifier ModifierFunc) []Expression {
+	modified := make([]Expression, len(exps))
+	for i, e := range exps {
+		modified[i] = modifyExpression(e, modifier)
+	}
+
+	return modified
+}
+
+func modifyIdentifiers(idents []*Identifier, modifier ModifierFunc) []*Identifier {
+	modified := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		modified[i] = modifyIdentifier(ident, modifier)
+	}
+
+	return modified
+}
+
+// modifyExpression modifies e, which may be nil.
+func modifyExpression(e Expression, modifier ModifierFunc) Expression {
+	if e == nil {
+		return nil
+	}
+
+	modified, _ := Modify(e, modifier).(Expression)
+	return modified
+}
+
+// modifyIdentifier modifies ident, which may be nil.
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+
+	modified, _ := Modify(ident, modifier).(*Identifier)
+	return modified
+}
+
+// modifyBlock modifies b, which may be nil.
+func modifyBlock(b *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+
+	modified, _ := Modify(b, modifier).(*BlockStatement)
+	return modified
+}
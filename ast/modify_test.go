@@ -0,0 +1,206 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+	
+	"github.com/adamwoolhether/monkeyLang/token"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+	
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+		
+		if integer.Value != 1 {
+			return node
+		}
+		
+		return &IntegerLiteral{Value: 2}
+	}
+	
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+		},
+		{&InfixExpression{Left: one(), Operator: "+", Right: two()}, &InfixExpression{Left: two(), Operator: "+", Right: two()}},
+		{&PrefixExpression{Operator: "-", Right: one()}, &PrefixExpression{Operator: "-", Right: two()}},
+		{&IndexExpression{Left: one(), Index: one()}, &IndexExpression{Left: two(), Index: two()}},
+		{
+			&IfExpression{
+				Condition:   one(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&IfExpression{
+				Condition:   two(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{&ReturnStatement{ReturnValue: one()}, &ReturnStatement{ReturnValue: two()}},
+		{&LetStatement{Value: one()}, &LetStatement{Value: two()}},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{ident("a")},
+				Defaults:   map[string]Expression{"a": one()},
+				Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{ident("a")},
+				Defaults:   map[string]Expression{"a": two()},
+				Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{&ArrayLiteral{Elements: []Expression{one(), one()}}, &ArrayLiteral{Elements: []Expression{two(), two()}}},
+		{&CallExpression{Function: one(), Arguments: []Expression{one()}}, &CallExpression{Function: two(), Arguments: []Expression{two()}}},
+		{&AssignExpression{Target: one(), Value: one()}, &AssignExpression{Target: two(), Value: two()}},
+		{&WhileStatement{Condition: one(), Body: &BlockStatement{Statements: []Statement{}}}, &WhileStatement{Condition: two(), Body: &BlockStatement{Statements: []Statement{}}}},
+		{&ThrowStatement{Value: one()}, &ThrowStatement{Value: two()}},
+		{
+			&IfExpression{
+				Condition:   one(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&IfExpression{
+				Condition:   two(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+	}
+	
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+		
+		if !reflect.DeepEqual(modified, tt.expected) {
+			t.Errorf("not equal. got=%#v, want=%#v", modified, tt.expected)
+		}
+	}
+	
+	hashLiteral := &HashLiteral{
+		Pairs: map[Expression]Expression{
+			one(): one(),
+		},
+	}
+	
+	hashLiteral = Modify(hashLiteral, turnOneIntoTwo).(*HashLiteral)
+	
+	for key, val := range hashLiteral.Pairs {
+		if key.(*IntegerLiteral).Value != 2 {
+			t.Errorf("key is not %d, got=%d", 2, key.(*IntegerLiteral).Value)
+		}
+		if val.(*IntegerLiteral).Value != 2 {
+			t.Errorf("value is not %d, got=%d", 2, val.(*IntegerLiteral).Value)
+		}
+	}
+}
+
+func TestModifyLeavesOriginal(t *testing.T) {
+	ident := &Identifier{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"}
+	original := &InfixExpression{Left: ident, Operator: "+", Right: ident}
+	
+	modified := Modify(original, func(node Node) Node {
+		if _, ok := node.(*Identifier); ok {
+			return &Identifier{Token: token.Token{Type: token.IDENT, Literal: "y"}, Value: "y"}
+		}
+		return node
+	})
+	
+	if original.String() != "(x + x)" {
+		t.Errorf("original was modified. got=%q", original.String())
+	}
+	if modified.String() != "(y + y)" {
+		t.Errorf("wrong modification. got=%q", modified.String())
+	}
+}
+
+func TestModifyBindings(t *testing.T) {
+	rename := func(node Node) Node {
+		if id, ok := node.(*Identifier); ok {
+			return ident(id.Value + "2")
+		}
+		return node
+	}
+	
+	tests := []struct {
+		input    Node
+		expected string
+	}{
+		{&LetStatement{Token: token.Token{Literal: "let"}, Name: ident("x"), Value: ident("y")}, "let x2 = y2;"},
+		{
+			&DestructuringLetStatement{
+				Token:   token.Token{Literal: "let"},
+				Pattern: &ArrayPattern{Elements: []*Identifier{ident("a")}, Rest: ident("b")},
+				Value:   ident("xs"),
+			},
+			"let [a2, ...b2] = xs2;",
+		},
+		{
+			&DestructuringLetStatement{
+				Token:   token.Token{Literal: "let"},
+				Pattern: &HashPattern{Keys: []*Identifier{ident("a"), ident("b")}},
+				Value:   ident("h"),
+			},
+			"let {a2, b2} = h2;",
+		},
+		{
+			&TryStatement{
+				Token: token.Token{Literal: "try"},
+				Block: &BlockStatement{Statements: []Statement{}},
+				Param: ident("e"),
+				Catch: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("e")}}},
+			},
+			"try  catch (e2) e2",
+		},
+		{
+			&FunctionLiteral{
+				Token:      token.Token{Literal: "fn"},
+				Parameters: []*Identifier{ident("a"), ident("b"), ident("c")},
+				Defaults:   map[string]Expression{"b": ident("x"), "c": ident("y")},
+				Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("a")}}},
+			},
+			"fn(a2, b2 = x2, c2 = y2) a2",
+		},
+	}
+	
+	for _, tt := range tests {
+		modified := Modify(tt.input, rename)
+		if modified.String() != tt.expected {
+			t.Errorf("wrong modification. want=%q, got=%q", tt.expected, modified.String())
+		}
+	}
+}
+
+func TestModifyOrder(t *testing.T) {
+	fn := &FunctionLiteral{
+		Parameters: []*Identifier{ident("a"), ident("b"), ident("c")},
+		Defaults:   map[string]Expression{"b": intLit(1, 0), "c": intLit(2, 0)},
+		Rest:       ident("d"),
+		Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("a")}}},
+	}
+	
+	var modified []string
+	Modify(fn, func(node Node) Node {
+		switch n := node.(type) {
+		case *Identifier:
+			modified = append(modified, n.Value)
+		case *IntegerLiteral:
+			modified = append(modified, n.Token.Literal)
+		}
+		return node
+	})
+	
+	if expected := collect(fn); !reflect.DeepEqual(modified, expected) {
+		t.Errorf("nodes modified out of order. want=%v, got=%v", expected, modified)
+	}
+}
@@ -0,0 +1,179 @@
+package ast
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the AST rooted at node in depth-first order, children
+// in the order they appear in the source. It starts by calling
+// v.Visit(node); node must not be nil.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+
+	case *ExpressionStatement:
+		walkExpression(v, n.Expression)
+
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+
+	case *LetStatement:
+		walkIdentifier(v, n.Name)
+		walkExpression(v, n.Value)
+
+	case *DestructuringLetStatement:
+		if n.Pattern != nil {
+			Walk(v, n.Pattern)
+		}
+		walkExpression(v, n.Value)
+
+	case *ArrayPattern:
+		walkIdentifiers(v, n.Elements)
+		walkIdentifier(v, n.Rest)
+
+	case *HashPattern:
+		walkIdentifiers(v, n.Keys)
+
+	case *ReturnStatement:
+		walkExpression(v, n.ReturnValue)
+
+	case *WhileStatement:
+		walkExpression(v, n.Condition)
+		walkBlock(v, n.Body)
+
+	case *ForInStatement:
+		walkIdentifier(v, n.Key)
+		walkIdentifier(v, n.Value)
+		walkExpression(v, n.Iterable)
+		walkBlock(v, n.Body)
+
+	case *TryStatement:
+		walkBlock(v, n.Block)
+		walkIdentifier(v, n.Param)
+		walkBlock(v, n.Catch)
+		walkBlock(v, n.Finally)
+
+	case *ThrowStatement:
+		walkExpression(v, n.Value)
+
+	case *ParenExpression:
+		walkExpression(v, n.Expression)
+
+	case *PrefixExpression:
+		walkExpression(v, n.Right)
+
+	case *InfixExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Right)
+
+	case *AssignExpression:
+		walkExpression(v, n.Target)
+		walkExpression(v, n.Value)
+
+	case *IfExpression:
+		walkExpression(v, n.Condition)
+		walkBlock(v, n.Consequence)
+		walkBlock(v, n.Alternative)
+
+	case *IndexExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Index)
+
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			walkIdentifier(v, p)
+			if p != nil {
+				walkExpression(v, n.Defaults[p.Value])
+			}
+		}
+		walkIdentifier(v, n.Rest)
+		walkBlock(v, n.Body)
+
+	case *MacroLiteral:
+		walkIdentifiers(v, n.Parameters)
+		walkBlock(v, n.Body)
+
+	case *CallExpression:
+		walkExpression(v, n.Function)
+		walkExpressions(v, n.Arguments)
+
+	case *SpreadExpression:
+		walkExpression(v, n.Value)
+
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *HashLiteral:
+		for _, key := range n.Keys() {
+			walkExpression(v, key)
+			walkExpression(v, n.Pairs[key])
+		}
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the AST rooted at node in depth-first order like
+// Walk does, calling f(node) for each node. If f returns true, Inspect
+// goes on to the children of node, followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+func walkStatements(v Visitor, stmts []Statement) {
+	for _, s := range stmts {
+		if s != nil {
+			Walk(v, s)
+		}
+	}
+}
+
+func walkExpressions(v Visitor, exps []Expression) {
+	for _, e := range exps {
+		walkExpression(v, e)
+	}
+}
+
+func walkIdentifiers(v Visitor, idents []*Identifier) {
+	for _, ident := range idents {
+		walkIdentifier(v, ident)
+	}
+}
+
+// walkExpression walks e, which may be nil.
+func walkExpression(v Visitor, e Expression) {
+	if e != nil {
+		Walk(v, e)
+	}
+}
+
+// walkIdentifier walks ident, which may be nil.
+func walkIdentifier(v Visitor, ident *Identifier) {
+	if ident != nil {
+		Walk(v, ident)
+	}
+}
+
+// walkBlock walks b, which may be nil.
+func walkBlock(v Visitor, b *BlockStatement) {
+	if b != nil {
+		Walk(v, b)
+	}
+}
@@ -0,0 +1,169 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	
+	"github.com/adamwoolhether/monkeyLang/token"
+)
+
+func ident(name string) *Identifier {
+	return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+func intLit(value int64, offset int) *IntegerLiteral {
+	return &IntegerLiteral{
+		Token: token.Token{Type: token.INT, Literal: fmt.Sprint(value), Pos: token.Position{Offset: offset}},
+		Value: value,
+	}
+}
+
+// collect returns the identifiers and integers under node in the order Inspect visits them.
+func collect(node Node) []string {
+	var seen []string
+	Inspect(node, func(n Node) bool {
+		switch n := n.(type) {
+		case *Identifier:
+			seen = append(seen, n.Value)
+		case *IntegerLiteral:
+			seen = append(seen, n.Token.Literal)
+		}
+		return true
+	})
+	
+	return seen
+}
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		input    Node
+		expected []string
+	}{
+		{&LetStatement{Name: ident("x"), Value: intLit(1, 0)}, []string{"x", "1"}},
+		{
+			&IfExpression{
+				Condition:   ident("c"),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("a")}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("b")}}},
+			},
+			[]string{"c", "a", "b"},
+		},
+		{
+			&HashLiteral{Pairs: map[Expression]Expression{
+				intLit(3, 10): ident("c"),
+				intLit(1, 2):  ident("a"),
+				intLit(2, 6):  ident("b"),
+			}},
+			[]string{"1", "a", "2", "b", "3", "c"},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{ident("a"), ident("b")},
+				Defaults:   map[string]Expression{"b": intLit(2, 0)},
+				Rest:       ident("rest"),
+				Body:       &BlockStatement{Statements: []Statement{&ReturnStatement{ReturnValue: ident("a")}}},
+			},
+			[]string{"a", "b", "2", "rest", "a"},
+		},
+		{
+			&DestructuringLetStatement{
+				Pattern: &ArrayPattern{Elements: []*Identifier{ident("a")}, Rest: ident("b")},
+				Value:   ident("xs"),
+			},
+			[]string{"a", "b", "xs"},
+		},
+		{
+			&ForInStatement{
+				Key:      ident("k"),
+				Value:    ident("v"),
+				Iterable: ident("xs"),
+				Body:     &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("v")}}},
+			},
+			[]string{"k", "v", "xs", "v"},
+		},
+		{
+			&TryStatement{
+				Block:   &BlockStatement{Statements: []Statement{&ThrowStatement{Value: intLit(1, 0)}}},
+				Param:   ident("e"),
+				Catch:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("e")}}},
+				Finally: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("f")}}},
+			},
+			[]string{"1", "e", "e", "f"},
+		},
+		{
+			&CallExpression{
+				Function:  ident("f"),
+				Arguments: []Expression{&SpreadExpression{Value: ident("xs")}, &IndexExpression{Left: ident("h"), Index: intLit(0, 0)}},
+			},
+			[]string{"f", "xs", "h", "0"},
+		},
+	}
+	
+	for _, tt := range tests {
+		seen := collect(tt.input)
+		if !reflect.DeepEqual(seen, tt.expected) {
+			t.Errorf("wrong nodes visited for %s. want=%v, got=%v", tt.input, tt.expected, seen)
+		}
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := &Program{Statements: []Statement{
+		&ExpressionStatement{Expression: &FunctionLiteral{
+			Parameters: []*Identifier{ident("a")},
+			Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("a")}}},
+		}},
+		&ExpressionStatement{Expression: ident("b")},
+	}}
+	
+	var seen []string
+	Inspect(program, func(n Node) bool {
+		if id, ok := n.(*Identifier); ok {
+			seen = append(seen, id.Value)
+		}
+		_, isFn := n.(*FunctionLiteral)
+		return !isFn
+	})
+	
+	if !reflect.DeepEqual(seen, []string{"b"}) {
+		t.Errorf("wrong nodes visited. want=%v, got=%v", []string{"b"}, seen)
+	}
+}
+
+type depthVisitor struct {
+	depth    *int
+	maxDepth *int
+}
+
+func (v depthVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		*v.depth--
+		return nil
+	}
+	
+	*v.depth++
+	if *v.depth > *v.maxDepth {
+		*v.maxDepth = *v.depth
+	}
+	return v
+}
+
+func TestWalkVisitsNilAfterChildren(t *testing.T) {
+	// (1 + (2 * 3))
+	node := &InfixExpression{
+		Left:     intLit(1, 0),
+		Operator: "+",
+		Right:    &InfixExpression{Left: intLit(2, 0), Operator: "*", Right: intLit(3, 0)},
+	}
+	
+	depth, maxDepth := 0, 0
+	Walk(depthVisitor{&depth, &maxDepth}, node)
+	
+	if depth != 0 {
+		t.Errorf("Visit(nil) not called once per node. depth=%d", depth)
+	}
+	if maxDepth != 3 {
+		t.Errorf("wrong max depth. want=%d, got=%d", 3, maxDepth)
+	}
+}
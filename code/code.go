@@ -74,10 +74,8 @@ const (
 	OpTrue
 	OpFalse
 	// OpEqual and following comparison operators tell the VM
-	// to pop them off and push the result back on.
-	// `OpLessThan` is omitted because with compilation, we can
-	// simply reorder code and reuse OpGreaterThan, allowing for
-	// smaller instruction set and tighter VM loop.
+	// to pop them off and push the result back on. The other
+	// comparisons are defined further down.
 	OpEqual       // =
 	OpNotEqual    // !=
 	OpGreaterThan // >
@@ -130,6 +128,87 @@ const (
 	OpCurrentClosure
 	// OpGetFree enables retrieving free variables for compiling closures.
 	OpGetFree
+
+	// OpMod through OpShiftRight work like OpAdd, popping two operands
+	// and pushing the result. Apart from OpMod and OpPow, they only
+	// operate on integers.
+	OpMod        // %
+	OpPow        // **
+	OpBitAnd     // &
+	OpBitOr      // |
+	OpBitXor     // ^
+	OpShiftLeft  // <<
+	OpShiftRight // >>
+	// OpBitNot flips the bits of the integer on top of the stack.
+	OpBitNot // ~
+
+	// OpIter replaces the object on top of the stack
+	// with an iterator over its elements.
+	OpIter
+	// OpIterNext advances the iterator on top of the stack, pushing
+	// the next element, preceded by its key if the second operand
+	// is 1. Once the iterator is exhausted it's popped off the
+	// stack, and execution jumps to the first operand.
+	OpIterNext
+
+	// OpSetFree assigns to a closure's free variable.
+	OpSetFree
+	// OpAssignLocal assigns to a local variable, where OpSetLocal
+	// binds a new one: it writes through to the closures that have
+	// captured the variable, instead of replacing it in its slot.
+	OpAssignLocal
+	// OpCaptureLocal and OpCaptureFree push a local or free variable
+	// as it's being captured by OpClosure. Captured variables are
+	// shared, rather than copied, so closures can assign to them.
+	OpCaptureLocal
+	OpCaptureFree
+
+	// OpSetIndex pops a value, an index and the array or hash being
+	// indexed, assigns the value to the element at the index, and
+	// pushes the value back.
+	OpSetIndex
+	// OpUpdateIndex works like OpSetIndex, but first combines the
+	// element's current value with the popped value using the binary
+	// operator given by its operand, ex: OpAdd for `a[i] += 1`.
+	OpUpdateIndex
+
+	// OpUnpackArray pops an array and pushes its elements, given their
+	// number as the first operand. If the second operand is 1, an array
+	// of the elements left over is pushed last.
+	OpUnpackArray
+	// OpUnpackHash pops as many string keys as its operand says, along
+	// with the hash below them, and pushes the value of each key.
+	OpUnpackHash
+
+	// OpJumpIfArg jumps to its first operand if the caller passed an
+	// argument for the parameter given by its second operand, skipping
+	// over the computation of the parameter's default value.
+	OpJumpIfArg
+	// OpCallSpread calls a func like OpCall does, with its arguments
+	// spread out of arrays, the number of which is its operand.
+	OpCallSpread
+
+	// OpTry activates the entry of the function's exception handler
+	// table given by its operand, until the matching OpEndTry.
+	OpTry
+	OpEndTry
+	// OpThrow pops a value and raises it as an error.
+	OpThrow
+
+	// OpGreaterThanOrEqual works like OpGreaterThan, for `>=`.
+	OpGreaterThanOrEqual
+	// OpLessThan and OpLessThanOrEqual are the comparisons for `<`
+	// and `<=`. Rather than reordering the operands of OpGreaterThan
+	// and OpGreaterThanOrEqual, they keep them in source order, so
+	// that errors report the operands the way they were written.
+	OpLessThan
+	OpLessThanOrEqual
+
+	// OpQuote pushes a copy of the *object.Quote constant given by its
+	// first operand, in which the calls to unquote() are replaced by
+	// the values popped off the stack, the number of which is its
+	// second operand.
+	OpQuote
 )
 
 // Definition enables looking up how many operands and opcode has
@@ -141,36 +220,63 @@ type Definition struct {
 
 // definitions holds the map of opcodes and their definitions.
 var definitions = map[Opcode]*Definition{
-	OpConstant:       {"OpConstant", []int{2}}, // 16 bits wide.
-	OpAdd:            {"OpAdd", []int{}},       // Empty slice signifies no operands.
-	OpPop:            {"OpPop", []int{}},
-	OpSub:            {"OpSub", []int{}},
-	OpMul:            {"OpMul", []int{}},
-	OpDiv:            {"OpDiv", []int{}},
-	OpTrue:           {"OpTrue", []int{}},
-	OpFalse:          {"OpFalse", []int{}},
-	OpEqual:          {"OpEqual", []int{}},
-	OpNotEqual:       {"OpNotEqual", []int{}},
-	OpGreaterThan:    {"OpGreaterThan", []int{}},
-	OpMinus:          {"OpMinus", []int{}},
-	OpBang:           {"OpBang", []int{}},
-	OpJumpNotTruthy:  {"OpJumpNotTruthy", []int{2}},
-	OpJump:           {"OpJump", []int{2}},
-	OpNull:           {"OpNull", []int{}},
-	OpGetGlobal:      {"OpGetGlobal", []int{2}},
-	OpSetGlobal:      {"OpSetGlobal", []int{2}},
-	OpArray:          {"OpArray", []int{2}}, // 2 bytes wide, 65535 maximum elements.
-	OpHash:           {"OpHash", []int{2}},
-	OpIndex:          {"OpIndex", []int{}},
-	OpCall:           {"OpCall", []int{1}},
-	OpReturnValue:    {"OpReturnValue", []int{}},
-	OpReturn:         {"OpReturn", []int{}},
-	OpGetLocal:       {"OpGetLocal", []int{1}},
-	OpSetLocal:       {"OpSetLocal", []int{1}},
-	OpGetBuiltin:     {"OpGetBuiltin", []int{1}},
-	OpClosure:        {"OpClosure", []int{2, 1}},
-	OpGetFree:        {"OpGetFree", []int{1}},
-	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	OpConstant:           {"OpConstant", []int{2}}, // 16 bits wide.
+	OpAdd:                {"OpAdd", []int{}},       // Empty slice signifies no operands.
+	OpPop:                {"OpPop", []int{}},
+	OpSub:                {"OpSub", []int{}},
+	OpMul:                {"OpMul", []int{}},
+	OpDiv:                {"OpDiv", []int{}},
+	OpTrue:               {"OpTrue", []int{}},
+	OpFalse:              {"OpFalse", []int{}},
+	OpEqual:              {"OpEqual", []int{}},
+	OpNotEqual:           {"OpNotEqual", []int{}},
+	OpGreaterThan:        {"OpGreaterThan", []int{}},
+	OpGreaterThanOrEqual: {"OpGreaterThanOrEqual", []int{}},
+	OpMinus:              {"OpMinus", []int{}},
+	OpBang:               {"OpBang", []int{}},
+	OpJumpNotTruthy:      {"OpJumpNotTruthy", []int{2}},
+	OpJump:               {"OpJump", []int{2}},
+	OpNull:               {"OpNull", []int{}},
+	OpGetGlobal:          {"OpGetGlobal", []int{2}},
+	OpSetGlobal:          {"OpSetGlobal", []int{2}},
+	OpArray:              {"OpArray", []int{2}}, // 2 bytes wide, 65535 maximum elements.
+	OpHash:               {"OpHash", []int{2}},
+	OpIndex:              {"OpIndex", []int{}},
+	OpCall:               {"OpCall", []int{1}},
+	OpReturnValue:        {"OpReturnValue", []int{}},
+	OpReturn:             {"OpReturn", []int{}},
+	OpGetLocal:           {"OpGetLocal", []int{1}},
+	OpSetLocal:           {"OpSetLocal", []int{1}},
+	OpGetBuiltin:         {"OpGetBuiltin", []int{1}},
+	OpClosure:            {"OpClosure", []int{2, 1}},
+	OpGetFree:            {"OpGetFree", []int{1}},
+	OpCurrentClosure:     {"OpCurrentClosure", []int{}},
+	OpMod:                {"OpMod", []int{}},
+	OpPow:                {"OpPow", []int{}},
+	OpBitAnd:             {"OpBitAnd", []int{}},
+	OpBitOr:              {"OpBitOr", []int{}},
+	OpBitXor:             {"OpBitXor", []int{}},
+	OpShiftLeft:          {"OpShiftLeft", []int{}},
+	OpShiftRight:         {"OpShiftRight", []int{}},
+	OpBitNot:             {"OpBitNot", []int{}},
+	OpIter:               {"OpIter", []int{}},
+	OpIterNext:           {"OpIterNext", []int{2, 1}},
+	OpSetFree:            {"OpSetFree", []int{1}},
+	OpAssignLocal:        {"OpAssignLocal", []int{1}},
+	OpCaptureLocal:       {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:        {"OpCaptureFree", []int{1}},
+	OpSetIndex:           {"OpSetIndex", []int{}},
+	OpUpdateIndex:        {"OpUpdateIndex", []int{1}},
+	OpUnpackArray:        {"OpUnpackArray", []int{2, 1}},
+	OpUnpackHash:         {"OpUnpackHash", []int{2}},
+	OpJumpIfArg:          {"OpJumpIfArg", []int{2, 1}},
+	OpCallSpread:         {"OpCallSpread", []int{1}},
+	OpTry:                {"OpTry", []int{2}},
+	OpEndTry:             {"OpEndTry", []int{}},
+	OpThrow:              {"OpThrow", []int{}},
+	OpLessThan:           {"OpLessThan", []int{}},
+	OpLessThanOrEqual:    {"OpLessThanOrEqual", []int{}},
+	OpQuote:              {"OpQuote", []int{2, 1}},
 }
 
 // Lookup enables looking up opcodes in the definitions map.
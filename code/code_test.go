@@ -1,6 +1,10 @@
 package code
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/adamwoolhether/monkeyLang/token"
+)
 
 func TestMake(t *testing.T) {
 	tests := []struct {
@@ -87,3 +91,39 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestSourceMapLookup(t *testing.T) {
+	sm := SourceMap{
+		{Offset: 0, Pos: token.Position{Line: 1, Column: 1}},
+		{Offset: 4, Pos: token.Position{Line: 2, Column: 1}},
+		{Offset: 10, Pos: token.Position{Line: 2, Column: 3}},
+	}
+
+	tests := []struct {
+		offset   int
+		expected string
+		ok       bool
+	}{
+		{-1, "-", false},
+		{0, "1:1", true},
+		{3, "1:1", true},
+		{4, "2:1", true},
+		{9, "2:1", true},
+		{10, "2:3", true},
+		{100, "2:3", true},
+	}
+
+	for _, tt := range tests {
+		pos, ok := sm.Lookup(tt.offset)
+		if ok != tt.ok {
+			t.Errorf("offset %d: wrong ok. want=%t, got=%t", tt.offset, tt.ok, ok)
+		}
+		if pos.String() != tt.expected {
+			t.Errorf("offset %d: wrong position. want=%s, got=%s", tt.offset, tt.expected, pos)
+		}
+	}
+
+	if _, ok := SourceMap(nil).Lookup(0); ok {
+		t.Errorf("empty source map resolved an offset")
+	}
+}
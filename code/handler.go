@@ -0,0 +1,10 @@
+package code
+
+// Handler is an entry in a function's exception handler table, set
+// up for one of its try statements. An OpTry instruction activates
+// the handler until the matching OpEndTry; an error raised in the
+// meantime resumes execution at Target, with the error on the stack.
+type Handler struct {
+	Target int  // Offset of the instruction to resume at.
+	Catch  bool // Whether Target is a catch block, receiving the error as a hash, or a finally block rethrowing it.
+}
@@ -0,0 +1,35 @@
+package code
+
+import (
+	"sort"
+
+	"github.com/adamwoolhether/monkeyLang/token"
+)
+
+// SourceMapEntry marks the instruction at Offset, and every
+// instruction following it up to the next entry, as compiled
+// from the source code at Pos.
+type SourceMapEntry struct {
+	Offset int
+	Pos    token.Position
+}
+
+// SourceMap maps offsets into a set of Instructions back to the
+// source positions they were compiled from. Entries are sorted by
+// offset, and only added when the position changes, so the map
+// stays much smaller than the instructions it describes.
+type SourceMap []SourceMapEntry
+
+// Lookup returns the source position of the instruction at offset.
+// An offset within an instruction's operands resolves to the
+// position of the instruction itself.
+func (sm SourceMap) Lookup(offset int) (token.Position, bool) {
+	i := sort.Search(len(sm), func(i int) bool {
+		return sm[i].Offset > offset
+	})
+	if i == 0 {
+		return token.Position{}, false
+	}
+
+	return sm[i-1].Pos, true
+}
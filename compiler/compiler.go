@@ -12,13 +12,20 @@ import (
 	"github.com/adamwoolhether/monkeyLang/ast"
 	"github.com/adamwoolhether/monkeyLang/code"
 	"github.com/adamwoolhether/monkeyLang/object"
+	"github.com/adamwoolhether/monkeyLang/token"
 )
 
 // Bytecode contains compiler-generated instructions and
-// compiler-evaluated constants.
+// compiler-evaluated constants. SourceMap maps the main
+// program's instructions back to the source code, compiled
+// functions carry their own. NumLocals is the number of
+// local slots the main program's block scopes need.
 type Bytecode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
+	SourceMap    code.SourceMap
+	NumLocals    int
+	Handlers     []code.Handler
 }
 
 // EmittedInstruction allows keeping track of an instruction
@@ -34,6 +41,27 @@ type CompilationScope struct {
 	instructions        code.Instructions
 	lastInstruction     EmittedInstruction // The very last instruction emitted.
 	previousInstruction EmittedInstruction // The instruction emitted immediately before lastInstruction.
+	sourceMap           code.SourceMap     // Source positions of the instructions.
+	loops               []*loop            // The loops enclosing the current instruction, innermost last.
+	tries               []*tryBlock        // The try statements whose handlers are active there, innermost last.
+	handlers            []code.Handler     // The exception handler table.
+}
+
+// loop tracks the jumps needed by a loop's control statements.
+// A continue jumps straight back to start, while a break's
+// target isn't known until the whole loop has been compiled.
+type loop struct {
+	start     int   // The instruction a continue jumps to.
+	breaks    []int // The positions of the OpJumps emitted for breaks.
+	iterating bool  // Whether an iterator, which a break has to pop, is on the stack.
+}
+
+// tryBlock tracks a try statement, or the catch block of one with a
+// finally block, while its handler is active. A control statement
+// leaving it has to deactivate the handler and run the finally block.
+type tryBlock struct {
+	finally *ast.BlockStatement // nil if there's no finally block.
+	loops   int                 // How many loops enclose the try statement.
 }
 
 // Compiler holds generated bytecode('instruction'), a pool of constants.
@@ -44,6 +72,10 @@ type Compiler struct {
 
 	scopes     []CompilationScope
 	scopeIndex int
+
+	// srcPos is the source position of the node being compiled,
+	// recorded in the source map for each instruction emitted.
+	srcPos token.Position
 }
 
 func New() *Compiler {
@@ -79,6 +111,13 @@ func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
 
 // Compile determines how to handle given base on the node type.
 func (c *Compiler) Compile(node ast.Node) error {
+	// Instructions are mapped to the innermost node they're
+	// compiled from that has a known position.
+	if pos := node.Pos(); pos.IsValid() {
+		defer func(outer token.Position) { c.srcPos = outer }(c.srcPos)
+		c.srcPos = pos
+	}
+
 	switch n := node.(type) {
 	case *ast.Program:
 		for _, s := range n.Statements {
@@ -96,16 +135,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(code.OpPop) // clean the stack.
 
 	case *ast.InfixExpression:
-		if n.Operator == "<" {
-			if err := c.Compile(n.Right); err != nil {
-				return err
-			}
-
-			if err := c.Compile(n.Left); err != nil {
-				return err
-			}
-			c.emit(code.OpGreaterThan)
-			return nil
+		switch n.Operator {
+		case "&&", "||":
+			return c.compileLogicalExpression(n)
 		}
 
 		err := c.Compile(n.Left)
@@ -118,6 +150,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 
+		// Operators are mapped to the operator itself, rather than the
+		// start of the expression, to pinpoint errors like `a / b`.
+		c.srcPos = n.Token.Pos
 		switch n.Operator {
 		case "+":
 			c.emit(code.OpAdd)
@@ -127,8 +162,28 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
+		case "**":
+			c.emit(code.OpPow)
+		case "&":
+			c.emit(code.OpBitAnd)
+		case "|":
+			c.emit(code.OpBitOr)
+		case "^":
+			c.emit(code.OpBitXor)
+		case "<<":
+			c.emit(code.OpShiftLeft)
+		case ">>":
+			c.emit(code.OpShiftRight)
 		case ">":
 			c.emit(code.OpGreaterThan)
+		case ">=":
+			c.emit(code.OpGreaterThanOrEqual)
+		case "<":
+			c.emit(code.OpLessThan)
+		case "<=":
+			c.emit(code.OpLessThanOrEqual)
 		case "==":
 			c.emit(code.OpEqual)
 		case "!=":
@@ -141,6 +196,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 		integer := &object.Integer{Value: n.Value}
 		c.emit(code.OpConstant, c.addConstant(integer))
 
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: n.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+
 	case *ast.Boolean:
 		if n.Value {
 			c.emit(code.OpTrue)
@@ -148,6 +207,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpFalse)
 		}
 
+	case *ast.ParenExpression:
+		return c.Compile(n.Expression)
+
 	case *ast.PrefixExpression:
 		if err := c.Compile(n.Right); err != nil {
 			return err
@@ -158,6 +220,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpBang)
 		case "-":
 			c.emit(code.OpMinus)
+		case "~":
+			c.emit(code.OpBitNot)
 		default:
 			return fmt.Errorf("unknown operator %s", n.Operator)
 		}
@@ -174,8 +238,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 
+		// Blocks that don't end in an expression, like an empty
+		// block or one ending in a loop, produce null.
 		if c.lastInstructionIs(code.OpPop) {
 			c.removeLastPop()
+		} else if !c.lastInstructionIs(code.OpReturnValue) {
+			c.emit(code.OpNull)
 		}
 
 		// Emit an `OpJump` with a bogus value
@@ -193,6 +261,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 			if c.lastInstructionIs(code.OpPop) {
 				c.removeLastPop()
+			} else if !c.lastInstructionIs(code.OpReturnValue) {
+				c.emit(code.OpNull)
 			}
 		}
 
@@ -200,29 +270,150 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.changeOperand(jumpPos, afterAlternativePos)
 
 	case *ast.BlockStatement:
-		for _, s := range n.Statements {
-			if err := c.Compile(s); err != nil {
-				return err
-			}
+		c.enterBlockScope()
+		if err := c.compileStatements(n.Statements); err != nil {
+			return err
+		}
+		c.leaveBlockScope()
+
+	case *ast.WhileStatement:
+		loopStart := len(c.currentInstructions())
+
+		if err := c.Compile(n.Condition); err != nil {
+			return err
+		}
+
+		// Emit an `OpJumpNotTruthy` with a bogus value
+		exitPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+		c.enterLoop(loopStart)
+		if err := c.Compile(n.Body); err != nil {
+			return err
+		}
+		c.emit(code.OpJump, loopStart)
+		l := c.leaveLoop()
+
+		afterLoopPos := len(c.currentInstructions())
+		c.changeOperand(exitPos, afterLoopPos)
+		for _, breakPos := range l.breaks {
+			c.changeOperand(breakPos, afterLoopPos)
+		}
+
+	case *ast.ForInStatement:
+		if err := c.Compile(n.Iterable); err != nil {
+			return err
+		}
+		c.emit(code.OpIter)
+
+		// The loop variables are scoped to the body, which
+		// doesn't get a block scope of its own.
+		c.enterBlockScope()
+
+		withKey := 0
+		var key Symbol
+		if n.Key != nil {
+			key = c.symbolTable.Define(n.Key.Value)
+			withKey = 1
 		}
+		value := c.symbolTable.Define(n.Value.Value)
+
+		// Emit an `OpIterNext` with a bogus jump target
+		loopStart := c.emit(code.OpIterNext, 9999, withKey)
+
+		// The value is pushed last, so it's bound first.
+		c.storeSymbol(value)
+		if n.Key != nil {
+			c.storeSymbol(key)
+		}
+
+		c.enterLoop(loopStart)
+		c.currentLoop().iterating = true
+		if err := c.compileStatements(n.Body.Statements); err != nil {
+			return err
+		}
+		c.emit(code.OpJump, loopStart)
+		l := c.leaveLoop()
+		c.leaveBlockScope()
+
+		afterLoopPos := len(c.currentInstructions())
+		c.replaceInstruction(loopStart, code.Make(code.OpIterNext, afterLoopPos, withKey))
+		for _, breakPos := range l.breaks {
+			c.changeOperand(breakPos, afterLoopPos)
+		}
+
+	case *ast.BreakStatement:
+		l := c.currentLoop()
+		if l == nil {
+			return &CompileError{Pos: n.Pos(), Msg: "break outside of loop"}
+		}
+
+		if err := c.unwindTries(len(c.scopes[c.scopeIndex].loops)); err != nil {
+			return err
+		}
+
+		if l.iterating {
+			c.emit(code.OpPop)
+		}
+
+		// Emit an `OpJump` with a bogus value, patched once
+		// the end of the loop is known.
+		l.breaks = append(l.breaks, c.emit(code.OpJump, 9999))
+
+	case *ast.ContinueStatement:
+		l := c.currentLoop()
+		if l == nil {
+			return &CompileError{Pos: n.Pos(), Msg: "continue outside of loop"}
+		}
+
+		if err := c.unwindTries(len(c.scopes[c.scopeIndex].loops)); err != nil {
+			return err
+		}
+
+		c.emit(code.OpJump, l.start)
 
 	case *ast.LetStatement:
-		symbol := c.symbolTable.Define(n.Name.Value)
+		// The name is defined after compiling the value,
+		// which may refer to a variable it shadows.
+		if err := c.Compile(n.Value); err != nil {
+			return err
+		}
+
+		symbol, err := c.defineBinding(n.Name, n.IsConst())
+		if err != nil {
+			return err
+		}
+		c.storeSymbol(symbol)
 
+	case *ast.DestructuringLetStatement:
 		if err := c.Compile(n.Value); err != nil {
 			return err
 		}
 
-		if symbol.Scope == GlobalScope {
-			c.emit(code.OpSetGlobal, symbol.Index)
-		} else {
-			c.emit(code.OpSetLocal, symbol.Index)
+		c.srcPos = n.Pattern.Pos()
+		names, err := c.compilePattern(n.Pattern)
+		if err != nil {
+			return err
+		}
+
+		symbols := make([]Symbol, len(names))
+		for i, name := range names {
+			if symbols[i], err = c.defineBinding(name, n.IsConst()); err != nil {
+				return err
+			}
 		}
 
+		// The values are pushed in order, so they're bound last to first.
+		for i := len(symbols) - 1; i >= 0; i-- {
+			c.storeSymbol(symbols[i])
+		}
+
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(n)
+
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(n.Value)
 		if !ok {
-			return fmt.Errorf("undefined variable %s", n.Value)
+			return &CompileError{Pos: n.Pos(), Msg: fmt.Sprintf("undefined variable %s", n.Value)}
 		}
 
 		c.loadSymbol(symbol)
@@ -277,11 +468,27 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.symbolTable.DefineFunctionName(n.Name)
 		}
 
-		for _, p := range n.Parameters {
+		numParams := len(n.Parameters)
+		if n.Rest != nil {
+			numParams++
+		}
+
+		// Default values are compiled before the parameters after
+		// them are defined, so they can only refer to earlier ones.
+		for i, p := range n.Parameters {
+			if def, ok := n.Defaults[p.Value]; ok {
+				if err := c.compileDefault(i, def, numParams); err != nil {
+					return err
+				}
+			}
 			c.symbolTable.Define(p.Value)
 		}
+		if n.Rest != nil {
+			c.symbolTable.Define(n.Rest.Value)
+		}
 
-		if err := c.Compile(n.Body); err != nil {
+		// The body shares the parameters' scope.
+		if err := c.compileStatements(n.Body.Statements); err != nil {
 			return err
 		}
 
@@ -293,34 +500,67 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		freeSymbols := c.symbolTable.FreeSymbols
-		numLocals := c.symbolTable.numDefinitions
+		numLocals := c.symbolTable.NumLocals()
+		sourceMap := c.scopes[c.scopeIndex].sourceMap
+		handlers := c.scopes[c.scopeIndex].handlers
 		instructions := c.leaveScope()
 
 		for _, s := range freeSymbols {
-			c.loadSymbol(s)
+			c.captureSymbol(s)
 		}
 
 		compiledFn := &object.CompiledFunction{
 			Instructions:  instructions,
 			NumLocals:     numLocals,
 			NumParameters: len(n.Parameters),
+			NumDefaults:   len(n.Defaults),
+			Variadic:      n.Rest != nil,
+			Name:          n.Name,
+			Pos:           n.Pos(),
+			SourceMap:     sourceMap,
+			Handlers:      handlers,
 		}
 
 		fnIndex := c.addConstant(compiledFn)
 		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
 
+	case *ast.MacroLiteral:
+		return &CompileError{Pos: n.Pos(), Msg: "macro literal outside of a top-level let statement"}
+
 	case *ast.ReturnStatement:
 		if err := c.Compile(n.ReturnValue); err != nil {
 			return err
 		}
 
+		if err := c.unwindTries(0); err != nil {
+			return err
+		}
+
 		c.emit(code.OpReturnValue)
 
+	case *ast.TryStatement:
+		return c.compileTryStatement(n)
+
+	case *ast.ThrowStatement:
+		if err := c.Compile(n.Value); err != nil {
+			return err
+		}
+
+		c.emit(code.OpThrow)
+
 	case *ast.CallExpression:
+		if ast.IsCallOf(n, "quote") {
+			return c.compileQuote(n)
+		}
+
 		if err := c.Compile(n.Function); err != nil {
 			return err
 		}
 
+		if hasSpread(n.Arguments) {
+			return c.compileSpreadCall(n)
+		}
+
 		for _, a := range n.Arguments {
 			if err := c.Compile(a); err != nil {
 				return err
@@ -334,11 +574,323 @@ func (c *Compiler) Compile(node ast.Node) error {
 	return nil
 }
 
+// compileDefault emits the instructions giving parameter i its default
+// value, unless the caller passed an argument for it. Any locals the
+// default value needs go in a block after all numParams parameters.
+func (c *Compiler) compileDefault(i int, def ast.Expression, numParams int) error {
+	// Emit an `OpJumpIfArg` with a bogus jump target
+	jumpPos := c.emit(code.OpJumpIfArg, 9999, i)
+
+	c.enterBlockScope()
+	c.symbolTable.numDefinitions = numParams
+	if err := c.Compile(def); err != nil {
+		return err
+	}
+	c.leaveBlockScope()
+
+	c.emit(code.OpSetLocal, i)
+
+	afterDefaultPos := len(c.currentInstructions())
+	c.replaceInstruction(jumpPos, code.Make(code.OpJumpIfArg, afterDefaultPos, i))
+
+	return nil
+}
+
+// compileTryStatement compiles a try statement. Its blocks are
+// protected by exception handlers, which resume execution at the
+// catch block, binding the error to its parameter, or at a copy of
+// the finally block that rethrows the error once it's run:
+//
+//	OpTry <catch or finally>
+//	<try block>
+//	OpEndTry
+//	OpJump <done>
+//	<bind the error>       // catch
+//	OpTry <finally>        // if there's a finally block
+//	<catch block>
+//	OpEndTry               // if there's a finally block
+//	<finally block>        // done
+//	OpJump <end>
+//	<bind the error>       // finally
+//	<finally block>
+//	<load the error>
+//	OpThrow
+//
+// Without a finally block, the catch block is placed before the try
+// block instead, so execution can't fall out of it into the code after
+// the statement, which expects its last instruction to be the one run.
+func (c *Compiler) compileTryStatement(ts *ast.TryStatement) error {
+	if ts.Finally == nil {
+		jumpPos := c.emit(code.OpJump, 9999)
+
+		catch := c.addHandler(true)
+		if err := c.compileCatch(ts, catch, -1); err != nil {
+			return err
+		}
+		afterCatchPos := c.emit(code.OpJump, 9999)
+
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+		if err := c.compileProtected(ts.Block.Statements, catch, nil); err != nil {
+			return err
+		}
+
+		c.changeOperand(afterCatchPos, len(c.currentInstructions()))
+		return nil
+	}
+
+	handler := c.addHandler(ts.Catch != nil)
+	finally := handler
+	if ts.Catch != nil {
+		finally = c.addHandler(false)
+	}
+
+	if err := c.compileProtected(ts.Block.Statements, handler, ts.Finally); err != nil {
+		return err
+	}
+
+	if ts.Catch != nil {
+		jumpPos := c.emit(code.OpJump, 9999)
+
+		if err := c.compileCatch(ts, handler, finally); err != nil {
+			return err
+		}
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+	}
+
+	if err := c.Compile(ts.Finally); err != nil {
+		return err
+	}
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	// The error is set aside while the finally block runs, so control
+	// statements in it don't have to pop it off the stack. Its name
+	// is a keyword, which can't clash with the user's.
+	c.scopes[c.scopeIndex].handlers[finally].Target = len(c.currentInstructions())
+	c.enterBlockScope()
+	pending := c.symbolTable.Define("finally")
+	c.storeSymbol(pending)
+	if err := c.Compile(ts.Finally); err != nil {
+		return err
+	}
+	c.loadSymbol(pending)
+	c.emit(code.OpThrow)
+	c.leaveBlockScope()
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+// compileCatch compiles the catch block of ts as the target of the
+// handler at index catch. Unless finally is -1, the block is protected
+// by the handler at that index, which runs the finally block.
+func (c *Compiler) compileCatch(ts *ast.TryStatement, catch, finally int) error {
+	c.scopes[c.scopeIndex].handlers[catch].Target = len(c.currentInstructions())
+
+	c.enterBlockScope()
+	c.storeSymbol(c.symbolTable.Define(ts.Param.Value))
+
+	if finally == -1 {
+		if err := c.compileStatements(ts.Catch.Statements); err != nil {
+			return err
+		}
+	} else if err := c.compileProtected(ts.Catch.Statements, finally, ts.Finally); err != nil {
+		return err
+	}
+	c.leaveBlockScope()
+
+	return nil
+}
+
+// compileProtected compiles stmts with the exception handler at index
+// handler active, tracking it for control statements that leave them.
+func (c *Compiler) compileProtected(stmts []ast.Statement, handler int, finally *ast.BlockStatement) error {
+	c.emit(code.OpTry, handler)
+
+	scope := &c.scopes[c.scopeIndex]
+	scope.tries = append(scope.tries, &tryBlock{finally: finally, loops: len(scope.loops)})
+
+	if err := c.compileStatements(stmts); err != nil {
+		return err
+	}
+
+	tries := c.scopes[c.scopeIndex].tries
+	c.scopes[c.scopeIndex].tries = tries[:len(tries)-1]
+
+	c.emit(code.OpEndTry)
+
+	return nil
+}
+
+// addHandler adds an entry to the current scope's exception handler
+// table, returning its index. Its target is set once it's compiled.
+func (c *Compiler) addHandler(catch bool) int {
+	c.scopes[c.scopeIndex].handlers = append(c.scopes[c.scopeIndex].handlers, code.Handler{Target: 9999, Catch: catch})
+
+	return len(c.scopes[c.scopeIndex].handlers) - 1
+}
+
+// unwindTries compiles what a control statement runs before leaving
+// the try statements enclosed by at least loops loops: innermost first,
+// their handlers are deactivated and their finally blocks run.
+func (c *Compiler) unwindTries(loops int) error {
+	tries := c.scopes[c.scopeIndex].tries
+	defer func() { c.scopes[c.scopeIndex].tries = tries }()
+
+	for i := len(tries) - 1; i >= 0 && tries[i].loops >= loops; i-- {
+		c.emit(code.OpEndTry)
+
+		if tries[i].finally != nil {
+			// Control statements in the finally block only
+			// leave the try statements outside of this one.
+			c.scopes[c.scopeIndex].tries = tries[:i]
+			if err := c.Compile(tries[i].finally); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasSpread reports whether any of a call's arguments is spread.
+func hasSpread(args []ast.Expression) bool {
+	for _, a := range args {
+		if _, ok := a.(*ast.SpreadExpression); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileQuote compiles a call to quote(), whose argument is kept
+// unevaluated as a constant, except for the calls to unquote() within
+// it. Their arguments are compiled in the order they appear, and
+// OpQuote splices the values into a copy of the constant.
+func (c *Compiler) compileQuote(call *ast.CallExpression) error {
+	if len(call.Arguments) != 1 {
+		return &CompileError{Pos: call.Pos(), Msg: fmt.Sprintf("wrong number of arguments to quote: want=1, got=%d", len(call.Arguments))}
+	}
+
+	var unquotes []*ast.CallExpression
+	ast.Inspect(call.Arguments[0], func(node ast.Node) bool {
+		if !ast.IsCallOf(node, "unquote") {
+			return true
+		}
+		unquotes = append(unquotes, node.(*ast.CallExpression))
+		return false
+	})
+
+	for _, unquote := range unquotes {
+		if len(unquote.Arguments) != 1 {
+			return &CompileError{Pos: unquote.Pos(), Msg: fmt.Sprintf("wrong number of arguments to unquote: want=1, got=%d", len(unquote.Arguments))}
+		}
+		if err := c.Compile(unquote.Arguments[0]); err != nil {
+			return err
+		}
+	}
+
+	// The calls to unquote() are left in the constant
+	// without the arguments compiled above.
+	node := ast.Modify(call.Arguments[0], func(node ast.Node) ast.Node {
+		if !ast.IsCallOf(node, "unquote") {
+			return node
+		}
+		unquote := *node.(*ast.CallExpression)
+		unquote.Arguments = nil
+		return &unquote
+	})
+
+	c.emit(code.OpQuote, c.addConstant(&object.Quote{Node: node}), len(unquotes))
+
+	return nil
+}
+
+// compileSpreadCall compiles a call spreading arrays over its arguments.
+// Every argument is passed to OpCallSpread as an array: spread ones
+// as they are, the others wrapped in an array of their own.
+func (c *Compiler) compileSpreadCall(n *ast.CallExpression) error {
+	for _, a := range n.Arguments {
+		if spread, ok := a.(*ast.SpreadExpression); ok {
+			if err := c.Compile(spread.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := c.Compile(a); err != nil {
+			return err
+		}
+		c.emit(code.OpArray, 1)
+	}
+
+	c.emit(code.OpCallSpread, len(n.Arguments))
+
+	return nil
+}
+
+// compileLogicalExpression compiles `&&` and `||` so the right operand
+// is only evaluated when the left one doesn't already decide the result.
+// Either way the result is a boolean, based on the operands' truthiness.
+//
+//	a && b:                          a || b:
+//	  <a>                              <a>
+//	  OpJumpNotTruthy false            OpJumpNotTruthy right
+//	  <b>                              OpTrue
+//	  OpJumpNotTruthy false            OpJump end
+//	  OpTrue                         right:
+//	  OpJump end                       <b>
+//	false:                             OpJumpNotTruthy false
+//	  OpFalse                          OpTrue
+//	end:                               OpJump end
+//	                                 false:
+//	                                   OpFalse
+//	                                 end:
+func (c *Compiler) compileLogicalExpression(n *ast.InfixExpression) error {
+	if err := c.Compile(n.Left); err != nil {
+		return err
+	}
+
+	var jumpsToFalse, jumpsToEnd []int
+
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+	if n.Operator == "&&" {
+		jumpsToFalse = append(jumpsToFalse, jumpNotTruthyPos)
+	} else {
+		c.emit(code.OpTrue)
+		jumpsToEnd = append(jumpsToEnd, c.emit(code.OpJump, 9999))
+		c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+	}
+
+	if err := c.Compile(n.Right); err != nil {
+		return err
+	}
+
+	jumpsToFalse = append(jumpsToFalse, c.emit(code.OpJumpNotTruthy, 9999))
+	c.emit(code.OpTrue)
+	jumpsToEnd = append(jumpsToEnd, c.emit(code.OpJump, 9999))
+
+	for _, pos := range jumpsToFalse {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	c.emit(code.OpFalse)
+
+	for _, pos := range jumpsToEnd {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+
+	return nil
+}
+
 // Bytecode returns Bytecode from the compiler-generations instructions.
 func (c *Compiler) Bytecode() *Bytecode {
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
+		SourceMap:    c.scopes[c.scopeIndex].sourceMap,
+		NumLocals:    c.symbolTable.NumLocals(),
+		Handlers:     c.scopes[c.scopeIndex].handlers,
 	}
 }
 
@@ -356,10 +908,27 @@ func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	pos := c.addInstruction(ins)
 
 	c.setLastInstruction(op, pos)
+	c.addSourcePosition(pos)
 
 	return pos
 }
 
+// addSourcePosition maps the instruction at offset to the
+// source position currently being compiled, unless the
+// preceding instruction is already mapped to it.
+func (c *Compiler) addSourcePosition(offset int) {
+	if !c.srcPos.IsValid() {
+		return
+	}
+
+	sourceMap := c.scopes[c.scopeIndex].sourceMap
+	if len(sourceMap) > 0 && sourceMap[len(sourceMap)-1].Pos == c.srcPos {
+		return
+	}
+
+	c.scopes[c.scopeIndex].sourceMap = append(sourceMap, code.SourceMapEntry{Offset: offset, Pos: c.srcPos})
+}
+
 func (c *Compiler) currentInstructions() code.Instructions {
 	return c.scopes[c.scopeIndex].instructions
 }
@@ -400,6 +969,12 @@ func (c *Compiler) removeLastPop() {
 
 	c.scopes[c.scopeIndex].instructions = new
 	c.scopes[c.scopeIndex].lastInstruction = previous
+
+	sourceMap := c.scopes[c.scopeIndex].sourceMap
+	for len(sourceMap) > 0 && sourceMap[len(sourceMap)-1].Offset >= last.Position {
+		sourceMap = sourceMap[:len(sourceMap)-1]
+	}
+	c.scopes[c.scopeIndex].sourceMap = sourceMap
 }
 
 // changeOperand allows replacing the operand of an instruction.
@@ -431,6 +1006,16 @@ func (c *Compiler) enterScope() {
 	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
 }
 
+// enterBlockScope gives the statements that follow a
+// scope of their own, within the current function's.
+func (c *Compiler) enterBlockScope() {
+	c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveBlockScope() {
+	c.symbolTable = c.symbolTable.Outer
+}
+
 func (c *Compiler) leaveScope() code.Instructions {
 	instructions := c.currentInstructions()
 
@@ -441,6 +1026,34 @@ func (c *Compiler) leaveScope() code.Instructions {
 	return instructions
 }
 
+// enterLoop starts tracking the control statements of
+// a loop whose continues jump to start.
+func (c *Compiler) enterLoop(start int) {
+	c.scopes[c.scopeIndex].loops = append(c.scopes[c.scopeIndex].loops, &loop{start: start})
+}
+
+// leaveLoop stops tracking the innermost loop,
+// returning it so its breaks can be patched.
+func (c *Compiler) leaveLoop() *loop {
+	loops := c.scopes[c.scopeIndex].loops
+	l := loops[len(loops)-1]
+	c.scopes[c.scopeIndex].loops = loops[:len(loops)-1]
+
+	return l
+}
+
+// currentLoop returns the innermost loop of the current
+// scope, or nil if there isn't one. Loops don't extend into
+// the functions defined within them.
+func (c *Compiler) currentLoop() *loop {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+
+	return loops[len(loops)-1]
+}
+
 func (c *Compiler) replaceLastPopWithReturn() {
 	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
 	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
@@ -448,6 +1061,199 @@ func (c *Compiler) replaceLastPopWithReturn() {
 	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
 }
 
+// compileStatements compiles a list of statements
+// without giving them a block scope.
+func (c *Compiler) compileStatements(stmts []ast.Statement) error {
+	for _, s := range stmts {
+		if err := c.Compile(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// defineBinding defines the name bound by a let or
+// const statement, which is a constant for the latter.
+// A constant can't be bound again in the same scope.
+func (c *Compiler) defineBinding(name *ast.Identifier, isConst bool) (Symbol, error) {
+	if c.symbolTable.DefinesConst(name.Value) {
+		return Symbol{}, &CompileError{Pos: name.Pos(), Msg: fmt.Sprintf("cannot redeclare constant %s", name.Value)}
+	}
+
+	if isConst {
+		return c.symbolTable.DefineConst(name.Value), nil
+	}
+
+	return c.symbolTable.Define(name.Value), nil
+}
+
+// compilePattern destructures the value on top of the stack, replacing
+// it with the values the pattern matches, in the order of the names
+// it returns.
+func (c *Compiler) compilePattern(pattern ast.Pattern) ([]*ast.Identifier, error) {
+	switch pattern := pattern.(type) {
+	case *ast.ArrayPattern:
+		names := append([]*ast.Identifier{}, pattern.Elements...)
+
+		withRest := 0
+		if pattern.Rest != nil {
+			names = append(names, pattern.Rest)
+			withRest = 1
+		}
+
+		c.emit(code.OpUnpackArray, len(pattern.Elements), withRest)
+
+		return names, nil
+
+	case *ast.HashPattern:
+		for _, key := range pattern.Keys {
+			str := &object.String{Value: key.Value}
+			c.emit(code.OpConstant, c.addConstant(str))
+		}
+
+		c.emit(code.OpUnpackHash, len(pattern.Keys))
+
+		return pattern.Keys, nil
+
+	default:
+		return nil, fmt.Errorf("unknown pattern %T", pattern)
+	}
+}
+
+// storeSymbol binds the value on top of the stack to
+// the newly defined s.
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpSetLocal, s.Index)
+	}
+}
+
+// assignSymbol assigns the value on top of the stack to s.
+// Unlike storeSymbol, it preserves the bindings of captured
+// variables, which are shared with closures.
+func (c *Compiler) assignSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpAssignLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
+
+// captureSymbol pushes the variable s for a closure to capture.
+func (c *Compiler) captureSymbol(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpCaptureLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpCaptureFree, s.Index)
+	default:
+		c.loadSymbol(s)
+	}
+}
+
+// compoundAssignOps maps compound assignment
+// operators to the opcode of their infix operator.
+var compoundAssignOps = map[string]code.Opcode{
+	"+=": code.OpAdd,
+	"-=": code.OpSub,
+	"*=": code.OpMul,
+	"/=": code.OpDiv,
+}
+
+// compileAssignExpression stores a new value in the binding the target
+// resolves to, and then loads it again as the result of the expression.
+// A compound assignment like 'x += 1' first applies its operator to the
+// binding's current value.
+func (c *Compiler) compileAssignExpression(n *ast.AssignExpression) error {
+	switch target := ast.Unparen(n.Target).(type) {
+	case *ast.Identifier:
+		return c.compileIdentifierAssignment(n, target)
+	case *ast.IndexExpression:
+		return c.compileIndexAssignment(n, target)
+	default:
+		return &CompileError{Pos: n.Target.Pos(), Msg: fmt.Sprintf("cannot assign to %s", n.Target)}
+	}
+}
+
+// compileIndexAssignment assigns to an element of an array or hash. The
+// indexed object and the index are only evaluated once, even for compound
+// assignments, which are left to OpUpdateIndex.
+func (c *Compiler) compileIndexAssignment(n *ast.AssignExpression, target *ast.IndexExpression) error {
+	if err := c.Compile(target.Left); err != nil {
+		return err
+	}
+	if err := c.Compile(target.Index); err != nil {
+		return err
+	}
+	if err := c.Compile(n.Value); err != nil {
+		return err
+	}
+
+	c.srcPos = n.Token.Pos
+	if n.Operator == "=" {
+		c.emit(code.OpSetIndex)
+		return nil
+	}
+
+	op, ok := compoundAssignOps[n.Operator]
+	if !ok {
+		return fmt.Errorf("unknown operator %s", n.Operator)
+	}
+	c.emit(code.OpUpdateIndex, int(op))
+
+	return nil
+}
+
+// compileIdentifierAssignment assigns to a global, local or free variable.
+func (c *Compiler) compileIdentifierAssignment(n *ast.AssignExpression, ident *ast.Identifier) error {
+	symbol, ok := c.symbolTable.Resolve(ident.Value)
+	if !ok {
+		return &CompileError{Pos: ident.Pos(), Msg: fmt.Sprintf("undefined variable %s", ident.Value)}
+	}
+
+	switch {
+	case symbol.Scope == BuiltinScope:
+		return &CompileError{Pos: ident.Pos(), Msg: fmt.Sprintf("cannot assign to builtin %s", ident.Value)}
+	case symbol.Scope == FunctionScope || symbol.Self:
+		return &CompileError{Pos: ident.Pos(), Msg: fmt.Sprintf("cannot assign to %s inside its own body", ident.Value)}
+	}
+
+	if symbol.Const {
+		return &CompileError{Pos: ident.Pos(), Msg: fmt.Sprintf("cannot assign to constant %s", ident.Value)}
+	}
+
+	if n.Operator == "=" {
+		if err := c.Compile(n.Value); err != nil {
+			return err
+		}
+	} else {
+		op, ok := compoundAssignOps[n.Operator]
+		if !ok {
+			return fmt.Errorf("unknown operator %s", n.Operator)
+		}
+
+		c.loadSymbol(symbol)
+		if err := c.Compile(n.Value); err != nil {
+			return err
+		}
+
+		c.srcPos = n.Token.Pos
+		c.emit(op)
+	}
+
+	c.assignSymbol(symbol)
+	c.loadSymbol(symbol)
+
+	return nil
+}
+
 func (c *Compiler) loadSymbol(s Symbol) {
 	switch s.Scope {
 	case GlobalScope:
@@ -0,0 +1,20 @@
+package compiler
+
+import "github.com/adamwoolhether/monkeyLang/token"
+
+// CompileError describes a program the compiler rejects even though
+// it parses, ex: an assignment to a constant. Pos locates the
+// offending node in the source code.
+type CompileError struct {
+	Pos token.Position
+	Msg string
+}
+
+// Error returns the message prefixed by its source position.
+func (e *CompileError) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Msg
+	}
+
+	return e.Msg
+}
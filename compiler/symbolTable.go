@@ -15,6 +15,8 @@ type Symbol struct {
 	Name  string
 	Scope SymbolScope
 	Index int
+	Const bool // Whether the symbol is a constant, which can't be assigned to.
+	Self  bool // Whether the free symbol is the name of an enclosing function, which can't be assigned to either.
 }
 
 // SymbolTable helps associate identifiers in the global scope with a
@@ -26,6 +28,15 @@ type SymbolTable struct {
 	store          map[string]Symbol
 	numDefinitions int
 	FreeSymbols    []Symbol
+
+	// block is set for the tables of block statements. Their symbols
+	// are locals of the enclosing function, or of the main program,
+	// stored in the slots following those of the enclosing scope, so
+	// sibling blocks reuse the same slots.
+	block bool
+	// maxBlockLocals is the number of local slots a function's, or
+	// the main program's, block scopes need at most.
+	maxBlockLocals int
 }
 
 func NewSymbolTable() *SymbolTable {
@@ -42,6 +53,22 @@ func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
 	return s
 }
 
+// NewBlockSymbolTable creates the table of a block statement nested
+// in outer. Blocks share the frame of the function they're in, or of
+// the main program, so their locals start where outer's leave off.
+func NewBlockSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewEnclosedSymbolTable(outer)
+	s.block = true
+
+	// The main program's own symbols are globals, and
+	// don't take up any of its local slots.
+	if outer.block || outer.Outer != nil {
+		s.numDefinitions = outer.numDefinitions
+	}
+
+	return s
+}
+
 func (s *SymbolTable) Define(name string) Symbol {
 	symbol := Symbol{
 		Name:  name,
@@ -56,9 +83,56 @@ func (s *SymbolTable) Define(name string) Symbol {
 	s.store[name] = symbol
 	s.numDefinitions++
 
+	if s.block {
+		frame := s.frame()
+		if s.numDefinitions > frame.maxBlockLocals {
+			frame.maxBlockLocals = s.numDefinitions
+		}
+	}
+
+	return symbol
+}
+
+// DefineConst defines name like Define does, as a constant.
+func (s *SymbolTable) DefineConst(name string) Symbol {
+	symbol := s.Define(name)
+	symbol.Const = true
+	s.store[name] = symbol
+
 	return symbol
 }
 
+// DefinesConst reports whether name is defined as a constant
+// in s itself, rather than in one of its outer tables.
+func (s *SymbolTable) DefinesConst(name string) bool {
+	return s.store[name].Const
+}
+
+// frame returns the table of the function, or the main
+// program, whose frame holds the locals of s.
+func (s *SymbolTable) frame() *SymbolTable {
+	for s.block {
+		s = s.Outer
+	}
+
+	return s
+}
+
+// NumLocals returns the number of local slots needed by the frame
+// of the function, or of the main program, that s belongs to.
+func (s *SymbolTable) NumLocals() int {
+	frame := s.frame()
+	if frame.Outer == nil {
+		return frame.maxBlockLocals
+	}
+
+	if frame.numDefinitions > frame.maxBlockLocals {
+		return frame.numDefinitions
+	}
+
+	return frame.maxBlockLocals
+}
+
 func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
 	obj, ok := s.store[name]
 	if !ok && s.Outer != nil {
@@ -67,7 +141,9 @@ func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
 			return obj, ok
 		}
 
-		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+		// Blocks share the frame of their outer scope,
+		// so none of its symbols are free in a block.
+		if s.block || obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
 			return obj, ok
 		}
 
@@ -95,6 +171,8 @@ func (s *SymbolTable) defineFree(original Symbol) Symbol {
 	symbol := Symbol{
 		Name:  original.Name,
 		Index: len(s.FreeSymbols) - 1,
+		Const: original.Const,
+		Self:  original.Self || original.Scope == FunctionScope,
 	}
 	symbol.Scope = FreeScope
 
@@ -244,6 +244,35 @@ func TestResolveFree(t *testing.T) {
 	}
 }
 
+func TestResolveFreeConst(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineConst("a")
+
+	local := NewEnclosedSymbolTable(global)
+	local.DefineConst("b")
+	local.Define("c")
+
+	nested := NewEnclosedSymbolTable(local)
+
+	expected := []Symbol{
+		Symbol{Name: "a", Scope: GlobalScope, Index: 0, Const: true},
+		Symbol{Name: "b", Scope: FreeScope, Index: 0, Const: true},
+		Symbol{Name: "c", Scope: FreeScope, Index: 1},
+	}
+
+	for _, sym := range expected {
+		result, ok := nested.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v",
+				sym.Name, sym, result)
+		}
+	}
+}
+
 func TestResolveUnresolvableFree(t *testing.T) {
 	global := NewSymbolTable()
 	global.Define("a")
@@ -317,3 +346,79 @@ func TestShadowingFunctionName(t *testing.T) {
 		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
 	}
 }
+
+func TestResolveBlock(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	firstBlock := NewBlockSymbolTable(global)
+	firstBlock.Define("b")
+	nestedBlock := NewBlockSymbolTable(firstBlock)
+	nestedBlock.Define("a")
+
+	secondBlock := NewBlockSymbolTable(global)
+	secondBlock.Define("c")
+
+	local := NewEnclosedSymbolTable(secondBlock)
+	local.Define("d")
+	localBlock := NewBlockSymbolTable(local)
+	localBlock.Define("e")
+
+	tests := []struct {
+		table           *SymbolTable
+		expectedSymbols []Symbol
+	}{
+		{
+			nestedBlock,
+			[]Symbol{
+				Symbol{Name: "a", Scope: LocalScope, Index: 1},
+				Symbol{Name: "b", Scope: LocalScope, Index: 0},
+			},
+		},
+		{
+			secondBlock,
+			[]Symbol{
+				Symbol{Name: "a", Scope: GlobalScope, Index: 0},
+				Symbol{Name: "c", Scope: LocalScope, Index: 0},
+			},
+		},
+		{
+			localBlock,
+			[]Symbol{
+				Symbol{Name: "a", Scope: GlobalScope, Index: 0},
+				Symbol{Name: "c", Scope: FreeScope, Index: 0},
+				Symbol{Name: "d", Scope: LocalScope, Index: 0},
+				Symbol{Name: "e", Scope: LocalScope, Index: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, sym := range tt.expectedSymbols {
+			result, ok := tt.table.Resolve(sym.Name)
+			if !ok {
+				t.Errorf("name %s not resolvable", sym.Name)
+				continue
+			}
+			if result != sym {
+				t.Errorf("expected %s to resolve to %+v, got=%+v",
+					sym.Name, sym, result)
+			}
+		}
+	}
+
+	if _, ok := global.Resolve("b"); ok {
+		t.Errorf("name b resolved outside of its block")
+	}
+
+	if len(local.FreeSymbols) != 1 {
+		t.Errorf("wrong number of free symbols. got=%d, want=1", len(local.FreeSymbols))
+	}
+
+	if n := global.NumLocals(); n != 2 {
+		t.Errorf("wrong number of locals for the main program. got=%d, want=2", n)
+	}
+	if n := local.NumLocals(); n != 2 {
+		t.Errorf("wrong number of locals for the function. got=%d, want=2", n)
+	}
+}
@@ -10,6 +10,9 @@ first([1, 2, 3]); // => 1
 last([1, 2, 3]); // => 3
 rest([1, 2, 3]); // => [2, 3]
 push([1, 2, 3], 4); // => [1, 2, 3, 4]
+int(2.7); // => 2
+float(2); // => 2.0
+range(3); // => range(0, 3)
 puts("Hello World!"); // prints "Hello World!"
 */
 
@@ -21,4 +24,7 @@ var builtins = map[string]*object.Builtin{
 	"last":  object.GetBuiltinByName("last"),
 	"rest":  object.GetBuiltinByName("rest"),
 	"push":  object.GetBuiltinByName("push"),
+	"int":   object.GetBuiltinByName("int"),
+	"float": object.GetBuiltinByName("float"),
+	"range": object.GetBuiltinByName("range"),
 }
@@ -4,20 +4,31 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/adamwoolhether/monkeyLang/ast"
 	"github.com/adamwoolhether/monkeyLang/object"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
+// MaxCallDepth is the number of nested function calls after which
+// evaluation fails, the same limit as the VM's call stack.
+const MaxCallDepth = 1024
+
+// callDepth is the number of function calls being evaluated.
+var callDepth int
+
 // isError is a helper funtion checking if an object is an error or not.
 func isError(obj object.Object) bool {
-	if obj == nil {
+	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
 	}
 
@@ -27,7 +38,32 @@ func isError(obj object.Object) bool {
 // Eval takes an ast.Node and returns an object.Object. Any node
 // that fulfills the ast.Node interface can be evaluated. Integer
 // and Boolean literals evaluate themselves.
+//
+// An error is stamped with the position of the innermost node
+// that produced it, which becomes the first frame of its trace.
+// Errors raised by an operator point at the operator itself.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+
+	if err, ok := result.(*object.Error); ok && err.Trace == nil {
+		pos := node.Pos()
+		switch node := node.(type) {
+		case *ast.InfixExpression:
+			pos = node.Token.Pos
+		case *ast.AssignExpression:
+			pos = node.Token.Pos
+		case *ast.LetStatement:
+			pos = node.Name.Pos()
+		case *ast.DestructuringLetStatement:
+			pos = node.Pattern.Pos()
+		}
+		err.Trace = object.StackTrace{{Pos: pos}}
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
@@ -38,8 +74,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.ParenExpression:
+		return Eval(node.Expression, env)
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
@@ -47,6 +87,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -57,7 +100,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return evalInfixExpression(node.Operator, left, right)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return evalBlockStatement(node, object.NewEnclosedEnvironment(env))
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 	case *ast.ReturnStatement:
@@ -66,28 +109,66 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return &object.Error{Message: object.ThrowMessage(val), Value: val}
 	case *ast.LetStatement:
+		if env.DefinesConst(node.Name.Value) {
+			return newError("cannot redeclare constant %s", node.Name.Value)
+		}
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.IsConst() {
+			env.SetConst(node.Name.Value, val)
+		} else {
+			env.Set(node.Name.Value, val)
+		}
+	case *ast.DestructuringLetStatement:
+		return evalDestructuringLetStatement(node, env)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Defaults: node.Defaults, Rest: node.Rest, Env: env, Body: body, Name: node.Name}
+	case *ast.MacroLiteral:
+		return newError("macro literal outside of a top-level let statement")
 	case *ast.CallExpression:
+		if ast.IsCallOf(node, "quote") {
+			return quote(node, env)
+		}
 		function := Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
+		args := evalArguments(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		result := applyFunction(function, args)
+		if err, ok := result.(*object.Error); ok && err.Trace != nil {
+			// The error unwound out of a Monkey function, so the
+			// caller's frame starts at this call.
+			err.Trace = append(err.Trace, object.StackFrame{Pos: node.Pos()})
+		}
+		return result
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.ArrayLiteral:
@@ -123,7 +204,10 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 		case *object.ReturnValue:
 			return result.Value
 		case *object.Error:
+			result.Trace[len(result.Trace)-1].Function = object.MainFunctionName
 			return result
+		case *object.Break, *object.Continue:
+			return newError("%s outside of loop", result.Inspect())
 		}
 	}
 
@@ -146,6 +230,8 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOPeratorExpression(right)
+	case "~":
+		return evalBitNotOperatorExpression(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -165,18 +251,31 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOPeratorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
+}
+
+func evalBitNotOperatorExpression(right object.Object) object.Object {
+	integer, ok := right.(*object.Integer)
+	if !ok {
+		return newError("unknown operator: ~%s", right.Type())
+	}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return &object.Integer{Value: ^integer.Value}
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
@@ -190,6 +289,10 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
+// evalBlockStatement evaluates the statements of a block in env. Blocks
+// get a scope of their own, except for function bodies, which share
+// the scope of their parameters, and loop bodies, which share the
+// scope of the loop variables.
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
@@ -198,15 +301,143 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
 	}
 
+	// Like a compiled block, one that doesn't end with
+	// an expression evaluates to null.
+	if result == nil {
+		return NULL
+	}
+
 	return result
 }
 
+// evalWhileStatement runs the loop's body until its condition is
+// no longer truthy or a break is encountered. Return values and
+// errors unwind out of the loop, loops evaluate to null otherwise.
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := Eval(ws.Body, env)
+		if result != nil {
+			switch result.Type() {
+			case object.BREAK_OBJ:
+				return NULL
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+				return result
+			}
+		}
+	}
+}
+
+// evalForInStatement runs the loop's body once for each element
+// of the iterable, binding the loop variables in a scope of their own
+// beforehand. It unwinds like evalWhileStatement.
+func evalForInStatement(fs *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	it, ok := iterable.(object.Iterable)
+	if !ok {
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	iter := it.Iter()
+	for {
+		key, value, ok := iter.Next()
+		if !ok {
+			return NULL
+		}
+
+		bodyEnv := object.NewEnclosedEnvironment(env)
+		bodyEnv.Set(fs.Value.Value, value)
+		if fs.Key != nil {
+			bodyEnv.Set(fs.Key.Value, key)
+		}
+
+		result := evalBlockStatement(fs.Body, bodyEnv)
+		if result != nil {
+			switch result.Type() {
+			case object.BREAK_OBJ:
+				return NULL
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+				return result
+			}
+		}
+	}
+}
+
+// evalTryStatement runs the try block, handing an error raised in it
+// to the catch block, if there is one. The finally block runs last,
+// however the others ended, and an error, return, break or continue
+// of its own takes precedence over theirs. Otherwise, an error that
+// wasn't caught keeps unwinding; a try statement evaluates to null.
+func evalTryStatement(ts *ast.TryStatement, env *object.Environment) object.Object {
+	result := Eval(ts.Block, env)
+
+	if err, ok := result.(*object.Error); ok && ts.Catch != nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+		catchEnv.Set(ts.Param.Value, object.ErrorHash(caughtError(err, env)))
+		result = evalBlockStatement(ts.Catch, catchEnv)
+	}
+
+	if ts.Finally != nil {
+		if finally := Eval(ts.Finally, env); isUnwinding(finally) {
+			return finally
+		}
+	}
+
+	if isUnwinding(result) {
+		return result
+	}
+
+	return NULL
+}
+
+// caughtError returns a copy of err as caught in env, naming the
+// frame of the function call that caught it in its trace.
+func caughtError(err *object.Error, env *object.Environment) *object.Error {
+	caught := *err
+	caught.Trace = append(object.StackTrace{}, err.Trace...)
+
+	name := env.Function()
+	if name == "" {
+		name = object.MainFunctionName
+	}
+	caught.Trace[len(caught.Trace)-1].Function = name
+
+	return &caught
+}
+
+// isUnwinding reports whether obj ends the evaluation of the blocks
+// enclosing it: an error, a return value, a break or a continue.
+func isUnwinding(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+
+	switch obj.Type() {
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
+	}
+
+	return false
+}
+
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
@@ -219,11 +450,43 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "**":
+		// A negative exponent yields a fraction.
+		if rightVal < 0 {
+			return &object.Float{Value: math.Pow(float64(leftVal), float64(rightVal))}
+		}
+		return &object.Integer{Value: integerPower(leftVal, rightVal)}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<", ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		if operator == "<<" {
+			return &object.Integer{Value: leftVal << rightVal}
+		}
+		return &object.Integer{Value: leftVal >> rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -233,6 +496,97 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
+// integerPower raises base to the non-negative exponent exp by
+// repeated squaring. Like the other integer operations, the
+// result wraps around on overflow.
+func integerPower(base, exp int64) int64 {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+
+	return result
+}
+
+// evalFloatInfixExpression evaluates operations on two numbers of
+// which at least one is a float. Integers are converted to floats,
+// so the result of arithmetic is always a float.
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// isNumber checks whether obj is an integer or a float.
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// toFloat returns the value of an integer or float object as a float64.
+func toFloat(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
+	}
+
+	return obj.(*object.Float).Value
+}
+
+// evalLogicalExpression evaluates `&&` and `||`, only evaluating the
+// right operand if the left one doesn't already decide the result.
+// The result is always a boolean, based on the operands' truthiness.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+
+	right := Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
 	if isError(condition) {
@@ -265,6 +619,58 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// evalDestructuringLetStatement binds the names of a pattern
+// to the parts of the value that they match.
+func evalDestructuringLetStatement(node *ast.DestructuringLetStatement, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	var (
+		names  []*ast.Identifier
+		values []object.Object
+		err    error
+	)
+	switch pattern := node.Pattern.(type) {
+	case *ast.ArrayPattern:
+		names = append(names, pattern.Elements...)
+		if pattern.Rest != nil {
+			names = append(names, pattern.Rest)
+		}
+		values, err = object.DestructureArray(val, len(pattern.Elements), pattern.Rest != nil)
+	case *ast.HashPattern:
+		names = pattern.Keys
+		keys := make([]string, len(pattern.Keys))
+		for i, key := range pattern.Keys {
+			keys[i] = key.Value
+		}
+		values, err = object.DestructureHash(val, keys)
+	default:
+		return newError("unknown pattern %T", node.Pattern)
+	}
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	for _, name := range names {
+		if env.DefinesConst(name.Value) {
+			err := newError("cannot redeclare constant %s", name.Value)
+			err.Trace = object.StackTrace{{Pos: name.Pos()}}
+			return err
+		}
+	}
+	for i, name := range names {
+		if node.IsConst() {
+			env.SetConst(name.Value, values[i])
+		} else {
+			env.Set(name.Value, values[i])
+		}
+	}
+
+	return nil
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
@@ -277,6 +683,114 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 	return newError("identifier not found: " + node.Value)
 }
 
+// evalAssignExpression rebinds the target in the environment defining
+// it, evaluating to the assigned value. A compound assignment like
+// 'x += 1' first applies its infix operator to the current value.
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	if target, ok := ast.Unparen(node.Target).(*ast.IndexExpression); ok {
+		return evalIndexAssignment(node, target, env)
+	}
+
+	ident, ok := ast.Unparen(node.Target).(*ast.Identifier)
+	if !ok {
+		return newError("cannot assign to %s", node.Target)
+	}
+
+	current, ok := env.Get(ident.Value)
+	if !ok {
+		if _, ok := builtins[ident.Value]; ok {
+			return newError("cannot assign to builtin %s", ident.Value)
+		}
+		return newError("identifier not found: " + ident.Value)
+	}
+	if env.IsFunctionName(ident.Value) {
+		return newError("cannot assign to %s inside its own body", ident.Value)
+	}
+	if env.IsConst(ident.Value) {
+		return newError("cannot assign to constant %s", ident.Value)
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	if node.Operator != "=" {
+		val = evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val)
+		if isError(val) {
+			return val
+		}
+	}
+
+	env.Assign(ident.Value, val)
+
+	return val
+}
+
+// evalIndexAssignment assigns to an element of an array or hash in place.
+func evalIndexAssignment(node *ast.AssignExpression, target *ast.IndexExpression, env *object.Environment) object.Object {
+	left := Eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	index := Eval(target.Index, env)
+	if isError(index) {
+		return index
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	if err := checkSetIndex(left, index); err != nil {
+		return err
+	}
+
+	if node.Operator != "=" {
+		current := evalIndexExpression(left, index)
+		val = evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val)
+		if isError(val) {
+			return val
+		}
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		left.Elements[index.(*object.Integer).Value] = val
+	case *object.Hash:
+		left.Pairs[index.(object.Hashable).HashKey()] = object.HashPair{Key: index, Value: val}
+	}
+
+	return val
+}
+
+// checkSetIndex returns an error if the element of left at index
+// can't be assigned to. Unlike reading an element, which results
+// in null if it doesn't exist, assigning to an array element
+// outside of the array's bounds is an error.
+func checkSetIndex(left, index object.Object) *object.Error {
+	switch left := left.(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be INTEGER, got %s", index.Type())
+		}
+		if i.Value < 0 || i.Value >= int64(len(left.Elements)) {
+			return newError("index out of range: %d with length %d", i.Value, len(left.Elements))
+		}
+	case *object.Hash:
+		if _, ok := index.(object.Hashable); !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+
+	return nil
+}
+
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 	for _, e := range exps {
@@ -290,11 +804,57 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
+// evalArguments evaluates the arguments of a call, spreading
+// the elements of the arrays marked with '...' over them.
+func evalArguments(exps []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+	for _, e := range exps {
+		spread, ok := e.(*ast.SpreadExpression)
+		if !ok {
+			evaluated := Eval(e, env)
+			if isError(evaluated) {
+				return []object.Object{evaluated}
+			}
+			result = append(result, evaluated)
+			continue
+		}
+
+		evaluated := Eval(spread.Value, env)
+		if isError(evaluated) {
+			return []object.Object{evaluated}
+		}
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			return []object.Object{newError("cannot spread %s", evaluated.Type())}
+		}
+		result = append(result, array.Elements...)
+	}
+
+	return result
+}
+
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
+		if err := object.CheckArity(len(args), len(fn.Parameters), len(fn.Defaults), fn.Rest != nil); err != nil {
+			return newError("%s", err)
+		}
+		if callDepth >= MaxCallDepth {
+			return newError("call stack overflow: exceeded %d nested calls", MaxCallDepth)
+		}
+		callDepth++
+		defer func() { callDepth-- }()
+
+		extendedEnv, evaluated := extendFunctionEnv(fn, args)
+		if evaluated == nil {
+			evaluated = evalBlockStatement(fn.Body, extendedEnv)
+		}
+		switch evaluated := evaluated.(type) {
+		case *object.Error:
+			evaluated.Trace[len(evaluated.Trace)-1].Function = object.FunctionName(fn.Name)
+		case *object.Break, *object.Continue:
+			return newError("%s outside of loop", evaluated.Inspect())
+		}
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
 		if result := fn.Fn(args...); result != nil {
@@ -306,14 +866,39 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+// extendFunctionEnv binds the parameters of fn to args. Parameters
+// without an argument are bound to their default value, evaluated in
+// the function's environment after the parameters before them. Like
+// the compiler, a default value can only refer to those, even from a
+// function it defines, so the parameters after it are bound in an
+// enclosed environment. An error evaluating a default value is
+// returned along with it.
+func extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, object.Object) {
+	env := object.NewCallEnvironment(fn.Env, object.FunctionName(fn.Name))
 
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(args) {
+			env.Set(param.Value, args[paramIdx])
+			continue
+		}
+
+		val := Eval(fn.Defaults[param.Value], env)
+		if isError(val) {
+			return env, val
+		}
+		env = object.NewEnclosedEnvironment(env)
+		env.Set(param.Value, val)
 	}
 
-	return env
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
+	}
+
+	return env, nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -340,6 +925,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -359,6 +946,20 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
+// evalStringIndexExpression returns the character at the given
+// rune index as a single-character string.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := str.(*object.String).Runes()
+	idx := index.(*object.Integer).Value
+	max := int64(len(runes) - 1)
+
+	if idx < 0 || idx > max {
+		return NULL
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
@@ -28,6 +28,20 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"0xFF + 0o10 + 0b1 + 1_000", 1264},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"3 ** 0", 1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 + 2 << 3", 24},
 	}
 	
 	for _, tt := range tests {
@@ -36,6 +50,42 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1.5", 1.5},
+		{"-2.25", -2.25},
+		{"1e3", 1000.0},
+		{"2.5e-1", 0.25},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2.0},
+		{"7 / 2.0", 3.5},
+		{"10 - 2.5 * 2", 5.0},
+		{"1.5 < 2", true},
+		{"2 > 2.5", false},
+		{"1 == 1.0", true},
+		{"1.5 != 1.5", false},
+		{"7.5 % 2", 1.5},
+		{"2.0 ** 3", 8.0},
+		{"4 ** 0.5", 2.0},
+		{"2 ** -1", 0.5},
+		{"1 / 0.0 > 1e308", true},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
 func TestEvalBooleanExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -60,6 +110,42 @@ func TestEvalBooleanExpressions(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"2.5 >= 2", true},
+		{"1 <= 0.5", false},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLogicalExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"true || false", true},
+		{"1 && \"a\"", true},
+		{"0 || false", true},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"false && true || true", true},
+		{"false && (true || true)", false},
+		// The right operand is only evaluated when needed, so the
+		// errors it would otherwise raise never happen.
+		{"false && 1()", false},
+		{"true || 1()", true},
 	}
 	
 	for _, tt := range tests {
@@ -100,6 +186,9 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (true) { }", nil},
+		{"let x = if (true) { let a = 1; }; x", nil},
+		{"fn() { let a = 1; }()", nil},
 	}
 	
 	for _, tt := range tests {
@@ -138,6 +227,94 @@ if (10 > 1) {
 	}
 }
 
+func TestWhileLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"while (false) { 10 }", nil},
+		{"let i = 0; while (i < 5) { i = i + 1; } i", 5},
+		{"let i = 0; while (true) { i = i + 1; if (i == 3) { break; } } i", 3},
+		{`
+let i = 0;
+let sum = 0;
+while (i < 10) {
+	i = i + 1;
+	if (i % 2 == 0) { continue; }
+	sum = sum + i;
+}
+sum`, 25},
+		{`
+let i = 0;
+let n = 0;
+while (i < 3) {
+	i = i + 1;
+	let j = 0;
+	while (true) {
+		j = j + 1;
+		if (j > i) { break; }
+		n = n + 1;
+	}
+}
+n`, 6},
+		{"let f = fn() { while (true) { return 7; } }; f()", 7},
+		{`
+let i = 0;
+while (i < 5000) {
+	i = i + 1;
+	if (i > 2) { if (true) { continue; } } else { try { continue; } finally { } }
+	let z = [1, 2];
+}
+i`, 5000},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForInLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"for (x in []) { 10 }", nil},
+		{"let sum = 0; for (x in [1, 2, 3]) { sum = sum + x; } sum", 6},
+		{"let sum = 0; for (i, x in [5, 6, 7]) { sum = sum + i * x; } sum", 20},
+		{`let sum = 0; for (k, v in {"a": 1, "b": 2}) { sum = sum + v; } sum`, 3},
+		{`let n = 0; for (c in "héllo") { n = n + 1; } n`, 5},
+		{"let sum = 0; for (i in range(5)) { sum = sum + i; } sum", 10},
+		{"let sum = 0; for (i in range(10, 0, -3)) { sum = sum + i; } sum", 22},
+		{`
+let sum = 0;
+for (x in range(1, 100)) {
+	if (x % 2 == 0) { continue; }
+	if (x > 10) { break; }
+	sum = sum + x;
+}
+sum`, 25},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10; } } }; f()", 20},
+		{"let s = []; for (x in [1, 2, 3]) { if (x == 2) { continue; } s = push(s, 10 + x); } s[0] + s[1]", 24},
+		{"let n = 0; for (x in range(5000)) { n = n + 1; if (x > 0) { if (true) { continue; } } let z = [x]; } n", 5000},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -182,6 +359,52 @@ return 1; }
 			`{"name": "Monkey"}[fn(x){ x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{"5 / 0", "division by zero"},
+		{"5 % (2 - 2)", "modulo by zero"},
+		{"let x = 1 / 0; x + 1", "division by zero"},
+		{"1 << -1", "negative shift count: -1"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"~true", "unknown operator: ~BOOLEAN"},
+		{"break", "break outside of loop"},
+		{"for (x in 5) { x }", "cannot iterate over INTEGER"},
+		{"x = 1", "identifier not found: x"},
+		{"if (true) { let x = 1 } x", "identifier not found: x"},
+		{"for (x in []) { } x = 1", "identifier not found: x"},
+		{"const x = 1; x = 2", "cannot assign to constant x"},
+		{"const c = 1; let c = 2; c = 3", "cannot redeclare constant c"},
+		{"let f = fn() { const c = 1; let [a, c] = [1, 2] }; f()", "cannot redeclare constant c"},
+		{"fn(a, b = 2) { a }()", "wrong number of arguments: want=1..2, got=0"},
+		{"fn(a, b = 2) { a }(1, 2, 3)", "wrong number of arguments: want=1..2, got=3"},
+		{"fn(a, ...r) { a }()", "wrong number of arguments: want=1 or more, got=0"},
+		{"fn(a) { a }(...[1, 2])", "wrong number of arguments: want=1, got=2"},
+		{"fn(a) { a }(...1)", "cannot spread INTEGER"},
+		{"fn(a = 1 + true) { a }()", "type mismatch: INTEGER + BOOLEAN"},
+		{"let [a, b] = [1]", "wrong number of elements to destructure: want=2, got=1"},
+		{"let [a] = [1, 2]", "wrong number of elements to destructure: want=1, got=2"},
+		{"let [a, b, ...c] = [1]", "wrong number of elements to destructure: want=2 or more, got=1"},
+		{"let [a] = {}", "cannot destructure HASH as an array"},
+		{`let {a} = {"b": 1}`, `hash has no key "a"`},
+		{"let {a} = [1]", "cannot destructure ARRAY as a hash"},
+		{"const x = 1; let f = fn() { x += 1 }; f()", "cannot assign to constant x"},
+		{"len = 1", "cannot assign to builtin len"},
+		{"let f = fn() { f = 1 }; f()", "cannot assign to f inside its own body"},
+		{"let f = fn() { fn() { f += 1 }() }; f()", "cannot assign to f inside its own body"},
+		{"let x = 1; x /= 0", "division by zero"},
+		{`let x = 1; x += "a"`, "type mismatch: INTEGER + STRING"},
+		{"[1, 2][2] = 0", "index out of range: 2 with length 2"},
+		{`[1, 2]["0"] = 0`, "array index must be INTEGER, got STRING"},
+		{"{}[fn() {}] = 1", "unusable as hash key: FUNCTION"},
+		{`"abc"[0] = "x"`, "index assignment not supported: STRING"},
+		{"let f = fn() { continue }; while (true) { f(); break }", "continue outside of loop"},
+		{"let m = macro(x) { x }; m(1)", "macro literal outside of a top-level let statement"},
+		{`throw "boom"`, "boom"},
+		{"throw 1 + 1", "2"},
+		{`try { 1 } finally { throw "from finally" }`, "from finally"},
+		{`let f = fn() { try { throw "a" } finally { 1 } }; f()`, "a"},
+		{`try { throw "a" } catch (e) { throw "b" } finally { 1 }`, "b"},
+		{"let f = fn(n) { f(n + 1) }; f(0)", "call stack overflow: exceeded 1024 nested calls"},
+		{"let f = fn(a = fn() { a }) { a }; f()()", "identifier not found: a"},
+		{"let f = fn(a = 1, b = fn() { c }, c = 3) { b() }; f()", "identifier not found: c"},
 	}
 	
 	for _, tt := range tests {
@@ -198,6 +421,136 @@ return 1; }
 	}
 }
 
+func TestIsError(t *testing.T) {
+	tests := []struct {
+		obj      object.Object
+		expected bool
+	}{
+		{nil, false},
+		{NULL, false},
+		{&object.Integer{Value: 1}, false},
+		{newError("boom"), true},
+	}
+	
+	for _, tt := range tests {
+		if got := isError(tt.obj); got != tt.expected {
+			t.Errorf("isError(%#v) wrong. want=%t, got=%t", tt.obj, tt.expected, got)
+		}
+	}
+}
+
+func TestStackTraces(t *testing.T) {
+	input := `let inner = fn(x) {
+  x + true
+};
+let outer = fn() {
+  inner(1)
+};
+outer();`
+	
+	evaluated := testEval(input)
+	
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	
+	expected := []struct {
+		function string
+		pos      string
+	}{
+		{"inner", "2:5"},
+		{"outer", "5:3"},
+		{"<main>", "7:1"},
+	}
+	
+	if len(errObj.Trace) != len(expected) {
+		t.Fatalf("wrong number of frames. want=%d, got=%d\n%s", len(expected), len(errObj.Trace), errObj.Trace)
+	}
+	
+	for i, frame := range expected {
+		if errObj.Trace[i].Function != frame.function || errObj.Trace[i].Pos.String() != frame.pos {
+			t.Errorf("frame %d wrong. want=%s at %s, got=%s", i, frame.function, frame.pos, errObj.Trace[i])
+		}
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let r = 0; try { throw 5; r = 1 } catch (e) { r = e["value"] } r`, 5},
+		{`let r = ""; try { 1 / 0 } catch (e) { r = e["message"] } r`, "division by zero"},
+		{`let r = ""; try { throw {"message": "from a hash"} } catch (e) { r = e["message"] } r`, "from a hash"},
+		{`let r = 0; try { 1 / 0 } catch (e) { r = e["value"] } r`, nil},
+		{`let r = ""; try { len(1) } catch (e) { r = e["message"] } r`, "argument to `len` not supported, got INTEGER"},
+		{
+			`let f = fn() { throw "deep" };
+			let g = fn() { f() };
+			let r = "";
+			try { g() } catch (e) { r = e["trace"][2] }
+			r`,
+			"at <main> (4:10)",
+		},
+		{
+			// A catch block only sees the frames up to its own.
+			`let f = fn() { throw "deep" };
+			let g = fn() { try { f() } catch (e) { return e["trace"][1] } };
+			g()`,
+			"at g (2:25)",
+		},
+		{`let r = ""; try { try { throw "a" } catch (e) { throw e } } catch (e) { r = e["message"] } r`, "a"},
+		{`let r = ""; try { try { throw "a" } catch (e) { throw "b" } } catch (e) { r = e["message"] } r`, "b"},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, 2},
+		{`let r = 0; let f = fn() { try { return 1 } finally { r = 2 } }; f() + r`, 3},
+		{`let f = fn() { try { throw "a" } catch (e) { return 1 } finally { 2 } }; f()`, 1},
+		{`let f = fn() { try { 1 } catch (e) { 2 } }; f()`, nil},
+		{
+			`let n = 0;
+			for (x in [1, 2, 3, 4]) {
+				try { if (x == 2) { continue } if (x == 4) { break } } finally { n += x }
+			}
+			n`,
+			10,
+		},
+		{
+			`let s = 0;
+			for (x in [1, 2, 3]) {
+				try { if (x == 2) { throw x } s += x } catch (e) { s += e["value"] * 10 }
+			}
+			s`,
+			24,
+		},
+		{
+			`let s = 0;
+			for (x in [1, 2]) { try { throw x } finally { s += x; break } }
+			s`,
+			1,
+		},
+	}
+	
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. want=%q, got=%q", expected, str.Value)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -207,6 +560,128 @@ func TestLetStatements(t *testing.T) {
 		{"let a = 5 * 5; a;", 25},
 		{"let a = 5; let b = a; b;", 5},
 		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"const a = 5; a;", 5},
+		{"const a = 5; if (true) { let a = 1; a = 2; } a;", 5},
+		{"const a = 5; let f = fn(a) { a += 1 }; f(1);", 2},
+		{"const a = 5; let f = fn() { let a = 1; a }; f() + a;", 6},
+	}
+	
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x += 2", 3},
+		{"let x = 10; x -= 4; x *= 3; x /= 2", 9},
+		{"let a = 1; let b = a = 5; a + b", 10},
+		{"let x = 1; let f = fn() { x = 2 }; f(); x", 2},
+		{"let f = fn(n) { n *= 2; n }; f(4)", 8},
+		{"let f = fn(f) { f = 1; f }; f(2)", 1},
+		{"let f = fn() { let f = 2; f += 1 }; f()", 3},
+		{"let x = 1; let f = fn(x) { x = 5 }; f(2); x", 1},
+		{"let counter = fn() { let c = 0; fn() { c += 1 } }; let next = counter(); next(); next(); next()", 3},
+		{"let i = 0; while (i < 5000) { i += 1 } i", 5000},
+		{"let sum = 0; for (x in range(101)) { sum += x } sum", 5050},
+	}
+	
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; if (true) { let x = 2; } x", 1},
+		{"let x = 1; if (true) { let x = x + 1; x }", 2},
+		{"let x = 1; if (true) { x = 2; } x", 2},
+		{"let f = fn(c) { if (c) { let a = 1; a } else { let b = 2; b } }; f(true) + f(false) * 10", 21},
+		{"let x = 1; for (x in [5]) { x } x", 1},
+		{"let fs = []; for (i in range(3)) { fs = push(fs, fn() { i }) } fs[0]() + fs[1]() * 10 + fs[2]() * 100", 210},
+		{"let fs = []; let i = 0; while (i < 3) { let j = i; fs = push(fs, fn() { j }); i += 1 } fs[0]() + fs[2]() * 10", 20},
+		{`
+let f = fn() {
+	let g = 0;
+	if (true) { let a = 1; g = fn() { a } }
+	let b = 2;
+	g() + b
+};
+f()`, 3},
+	}
+	
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, ...b] = [1, 2, 3]; a + len(b) * 10 + b[1] * 100", 321},
+		{"let [a, ...b] = [1]; len(b)", 0},
+		{"let xs = [1, 2]; let [...ys] = xs; ys[0] = 5; xs[0]", 1},
+		{`let {name, age} = {"name": 1, "age": 2, "other": 3}; name * 10 + age`, 12},
+		{"let f = fn(p) { let [x, y] = p; x - y }; f([5, 3])", 2},
+		{"let x = 1; if (true) { let [x] = [x + 1]; x }", 2},
+		{"let x = 1; if (true) { let [x] = [2]; } x", 1},
+		{"let fs = []; for (p in [[1, 2], [3, 4]]) { let [a, b] = p; fs = push(fs, fn() { a + b }) } fs[0]() * 10 + fs[1]()", 37},
+	}
+	
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = fn(a, b = 2) { a + b }; f(1)", 3},
+		{"let f = fn(a, b = 2) { a + b }; f(1, 5)", 6},
+		{"let f = fn(a, b = a * 10) { b }; f(3)", 30},
+		{"let x = 100; let f = fn(a = x) { a }; f()", 100},
+		{"let f = fn(a, ...r) { len(r) }; f(1)", 0},
+		{"let f = fn(a, ...r) { r[1] }; f(1, 2, 3)", 3},
+		{"let f = fn(a = 5, ...r) { a + len(r) }; f()", 5},
+		{"let f = fn(a = if (true) { let t = 1; let u = 2; t + u }, ...r) { a + len(r) }; f()", 3},
+		{"let f = fn(a, g = fn() { a }) { a = 2; g() }; f(1)", 2},
+		{"let a = 1; let f = fn(a = fn() { a }) { a }; f()()", 1},
+		{"let f = fn(a, b = fn() { a += 1 }) { b(); a }; f(1)", 2},
+		{"let f = fn(a = 1, b = fn() { a }) { a = 5; b() }; f()", 5},
+		{"let sum = fn(n, acc = 0) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(4)", 10},
+		{"let f = fn(...r) { len(r) }; f(...[1, 2], 3, ...[])", 3},
+		{"let f = fn(a, b, c) { a * 100 + b * 10 + c }; f(...[1, 2], 3)", 123},
+		{`len(...["abc"])`, 3},
+	}
+	
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestIndexAssignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = [1, 2, 3]; a[1] = 5; a[0] + a[1] + a[2]", 9},
+		{"let a = [1, 2, 3]; a[2] += 10", 13},
+		{"let a = [[1], [2]]; a[1][0] *= 7; a[1][0]", 14},
+		{`let h = {"a": 1}; h["a"] = 2; h["b"] = 3; h["a"] + h["b"]`, 5},
+		{"let a = [1]; let b = a; b[0] = 9; a[0]", 9},
+		{"let f = fn(xs) { xs[0] = 4 }; let a = [0]; f(a); a[0]", 4},
 	}
 	
 	for _, tt := range tests {
@@ -301,8 +776,22 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo wörld")`, 11},
+		{`len("日本語")`, 3},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`int(2.7)`, 2},
+		{`int(-2.7)`, -2},
+		{`int(" 42 ")`, 42},
+		{`int("4.2")`, `cannot convert "4.2" to INTEGER`},
+		{`int(true)`, "argument to `int` not supported, got BOOLEAN"},
+		{`float(2)`, 2.0},
+		{`float("1e-3")`, 0.001},
+		{`float(1.5)`, 1.5},
+		{`float("abc")`, `cannot convert "abc" to FLOAT`},
+		{`range(1, 2, 0)`, "`range` step must not be zero"},
+		{`range("3")`, "arguments to `range` must be INTEGER, got STRING"},
+		{`range()`, "wrong number of arguments. got=0, want=1..3"},
 	}
 	
 	for _, tt := range tests {
@@ -311,6 +800,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		switch expected := tt.expected.(type) {
 		case int:
 			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
 		case string:
 			errObj, ok := evaluated.(*object.Error)
 			if !ok {
@@ -400,6 +891,39 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"monkey"[0]`, "m"},
+		{`"monkey"[5]`, "y"},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[2]`, "l"},
+		{`let größe = "日本語"; größe[len(größe) - 1]`, "語"},
+		{`"monkey"[6]`, nil},
+		{`"monkey"[-1]`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		expected, ok := tt.expected.(string)
+		if !ok {
+			testNullObject(t, evaluated)
+			continue
+		}
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != expected {
+			t.Errorf("String has wrong value. want=%q, got=%q", expected, str.Value)
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -408,7 +932,8 @@ func TestHashLiterals(t *testing.T) {
 		"thr" + "ee": 6 / 2,
 		4: 4,
 		true: 5,
-		false: 6
+		false: 6,
+		7.5: 7
 	}`
 	
 	evaluated := testEval(input)
@@ -424,6 +949,7 @@ func TestHashLiterals(t *testing.T) {
 		(&object.Integer{Value: 4}).HashKey():      4,
 		TRUE.HashKey():                             5,
 		FALSE.HashKey():                            6,
+		(&object.Float{Value: 7.5}).HashKey():      7,
 	}
 	
 	if len(result.Pairs) != len(expected) {
@@ -464,6 +990,12 @@ func TestHashIndexExpresions(t *testing.T) {
 		{
 			`{false: 5}[false]`, 5,
 		},
+		{
+			`{1.5: 5}[1.5]`, 5,
+		},
+		{
+			`{1: 5}[1.0]`, 5,
+		},
 	}
 	
 	for _, tt := range tests {
@@ -500,6 +1032,20 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want =%g", result.Value, expected)
+		return false
+	}
+	
+	return true
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
@@ -0,0 +1,133 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/adamwoolhether/monkeyLang/ast"
+	"github.com/adamwoolhether/monkeyLang/object"
+	"github.com/adamwoolhether/monkeyLang/token"
+)
+
+// MacroError describes a macro call that couldn't be expanded.
+// Pos locates the call in the source code.
+type MacroError struct {
+	Pos token.Position
+	Msg string
+}
+
+// Error returns the message prefixed by its source position.
+func (e *MacroError) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Msg
+	}
+
+	return e.Msg
+}
+
+// DefineMacros binds the macros defined at the top level of program
+// in env, removing their definitions: let or const statements whose
+// value is a macro literal. Macros defined elsewhere are left alone.
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	statements := []ast.Statement{}
+
+	for _, statement := range program.Statements {
+		let, ok := statement.(*ast.LetStatement)
+		if !ok {
+			statements = append(statements, statement)
+			continue
+		}
+
+		lit, ok := let.Value.(*ast.MacroLiteral)
+		if !ok {
+			statements = append(statements, statement)
+			continue
+		}
+
+		env.Set(let.Name.Value, &object.Macro{Parameters: lit.Parameters, Body: lit.Body, Env: env})
+	}
+
+	program.Statements = statements
+}
+
+// ExpandMacros returns a copy of program in which each call of a macro
+// bound in env is replaced by the AST it returns, when called with the
+// AST of its arguments quoted. Expansion stops at the first macro call
+// that fails, with a *MacroError.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, error) {
+	var err error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil {
+			return node
+		}
+
+		macro, ok := macroCalled(call, env)
+		if !ok {
+			return node
+		}
+
+		expansion, msg := expandMacroCall(call, macro)
+		if msg != "" {
+			err = &MacroError{Pos: call.Pos(), Msg: msg}
+			return node
+		}
+
+		return expansion
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expanded, nil
+}
+
+// macroCalled returns the macro bound in env that call calls, if any.
+func macroCalled(call *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := env.Get(ident.Value)
+	if !ok {
+		return nil, false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
+
+// expandMacroCall evaluates the body of macro with its parameters bound
+// to the quoted arguments of call, returning the AST it quotes, or a
+// message describing why it couldn't.
+func expandMacroCall(call *ast.CallExpression, macro *object.Macro) (ast.Node, string) {
+	if len(call.Arguments) != len(macro.Parameters) {
+		return nil, fmt.Sprintf("wrong number of arguments to macro %s: want=%d, got=%d",
+			call.Function, len(macro.Parameters), len(call.Arguments))
+	}
+
+	env := object.NewEnclosedEnvironment(macro.Env)
+	for i, param := range macro.Parameters {
+		env.Set(param.Value, &object.Quote{Node: call.Arguments[i]})
+	}
+
+	evaluated := unwrapReturnValue(Eval(macro.Body, env))
+	switch evaluated := evaluated.(type) {
+	case *object.Quote:
+		return evaluated.Node, ""
+	case *object.Error:
+		return nil, fmt.Sprintf("macro %s failed: %s", call.Function, evaluated.Message)
+	default:
+		return nil, fmt.Sprintf("macro %s returned %s, not a quote", call.Function, typeOf(evaluated))
+	}
+}
+
+// typeOf returns the type of obj, which may be nil.
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+
+	return obj.Type()
+}
@@ -0,0 +1,175 @@
+package evaluator
+
+import (
+	"testing"
+	
+	"github.com/adamwoolhether/monkeyLang/ast"
+	"github.com/adamwoolhether/monkeyLang/lexer"
+	"github.com/adamwoolhether/monkeyLang/object"
+	"github.com/adamwoolhether/monkeyLang/parser"
+)
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	const other = macro() { 1 };
+	`
+	
+	env := object.NewEnvironment()
+	program := testParseProgram(input)
+	
+	DefineMacros(program, env)
+	
+	if len(program.Statements) != 2 {
+		t.Fatalf("Wrong number of statements. got=%d", len(program.Statements))
+	}
+	
+	if _, ok := env.Get("number"); ok {
+		t.Fatalf("number should not be defined")
+	}
+	if _, ok := env.Get("function"); ok {
+		t.Fatalf("function should not be defined")
+	}
+	
+	obj, ok := env.Get("mymacro")
+	if !ok {
+		t.Fatalf("macro not in environment.")
+	}
+	
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+	}
+	
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("Wrong number of macro parameters. got=%d", len(macro.Parameters))
+	}
+	if macro.Parameters[0].String() != "x" || macro.Parameters[1].String() != "y" {
+		t.Fatalf("parameters wrong. got=%v", macro.Parameters)
+	}
+	
+	expectedBody := "(x + y)"
+	if macro.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, macro.Body.String())
+	}
+	
+	if _, ok := env.Get("other"); !ok {
+		t.Fatalf("const macro not in environment.")
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`
+			let infixExpression = macro() { quote(1 + 2); };
+			
+			infixExpression();
+			`,
+			`(1 + 2)`,
+		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+			
+			reverse(2 + 2, 10 - 5);
+			`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`
+			let unless = macro(condition, consequence, alternative) {
+				quote(if (!(unquote(condition))) {
+					unquote(consequence);
+				} else {
+					unquote(alternative);
+				});
+			};
+			
+			unless(10 > 5, puts("not greater"), puts("greater"));
+			`,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+		},
+		{
+			// Each call is expanded with its own arguments.
+			`
+			let double = macro(x) { quote(unquote(x) * 2) };
+			
+			double(1) + double(a);
+			`,
+			`(1 * 2) + (a * 2)`,
+		},
+	}
+	
+	for _, tt := range tests {
+		expected := testParseProgram(tt.expected)
+		program := testParseProgram(tt.input)
+		
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("expansion error: %s", err)
+		}
+		
+		if expanded.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+		}
+	}
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let m = macro(a) { quote(a) }; m()", "1:32: wrong number of arguments to macro m: want=1, got=0"},
+		{"let m = macro() { 1 }; m()", "1:24: macro m returned INTEGER, not a quote"},
+		{"let m = macro() { }; m()", "1:22: macro m returned NULL, not a quote"},
+		{"let m = macro() { 1 + true }; m()", "1:31: macro m failed: type mismatch: INTEGER + BOOLEAN"},
+	}
+	
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+		
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		_, err := ExpandMacros(program, env)
+		if err == nil {
+			t.Errorf("%q: expected expansion error", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestMacrosBeforeEval(t *testing.T) {
+	input := `
+	let unless = macro(cond, cons, alt) { quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) }) };
+	let x = 0;
+	unless(x > 0, 10, 20)
+	`
+	
+	env := object.NewEnvironment()
+	program := testParseProgram(input)
+	DefineMacros(program, env)
+	expanded, err := ExpandMacros(program, env)
+	if err != nil {
+		t.Fatalf("expansion error: %s", err)
+	}
+	
+	testIntegerObject(t, Eval(expanded, object.NewEnvironment()), 10)
+}
+
+func testParseProgram(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
@@ -0,0 +1,47 @@
+package evaluator
+
+import (
+	"github.com/adamwoolhether/monkeyLang/ast"
+	"github.com/adamwoolhether/monkeyLang/object"
+)
+
+// quote returns the argument of a call to quote() unevaluated, except
+// for the calls to unquote() within it, which are replaced by the AST
+// of their evaluated argument.
+func quote(call *ast.CallExpression, env *object.Environment) object.Object {
+	if len(call.Arguments) != 1 {
+		return newError("wrong number of arguments to quote: want=1, got=%d", len(call.Arguments))
+	}
+
+	var err object.Object
+	node := ast.Modify(call.Arguments[0], func(node ast.Node) ast.Node {
+		if err != nil || !ast.IsCallOf(node, "unquote") {
+			return node
+		}
+		call := node.(*ast.CallExpression)
+
+		if len(call.Arguments) != 1 {
+			err = newError("wrong number of arguments to unquote: want=1, got=%d", len(call.Arguments))
+			return node
+		}
+
+		unquoted := Eval(call.Arguments[0], env)
+		if isError(unquoted) {
+			err = unquoted
+			return node
+		}
+
+		converted, ok := object.ASTNode(unquoted)
+		if !ok {
+			err = newError("cannot unquote %s", unquoted.Type())
+			return node
+		}
+
+		return converted
+	})
+	if err != nil {
+		return err
+	}
+
+	return &object.Quote{Node: node}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/adamwoolhether/monkeyLang/format"
+)
+
+// sourceExt is the extension of the files 'monkey fmt' looks for in directories.
+const sourceExt = ".mk"
+
+const fmtUsage = `usage: monkey fmt [-w] [-l] [-check] [path ...]
+
+Fmt formats Monkey source files in canonical style. Without paths, it
+formats standard input to standard output. Directories are searched
+for *.mk files recursively.
+
+`
+
+// fmtCommand runs 'monkey fmt' with the given args, returning the exit
+// status: 1 if -check found files that need formatting, 2 on errors.
+func fmtCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("fmt", fmtUsage, stderr)
+	write := flags.Bool("w", false, "write the result to the source file instead of standard output")
+	list := flags.Bool("l", false, "list the files whose formatting differs from fmt's")
+	check := flags.Bool("check", false, "like -l, exiting with status 1 if any file needs formatting")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(stderr, "monkey fmt: cannot use -w with standard input")
+			return 2
+		}
+
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+			return 2
+		}
+
+		res, err := format.Source(src)
+		if err != nil {
+			fmt.Fprintf(stderr, "<standard input>:%s\n", err)
+			return 2
+		}
+
+		switch {
+		case *list || *check:
+			if !bytes.Equal(src, res) {
+				fmt.Fprintln(stdout, "<standard input>")
+				if *check {
+					return 1
+				}
+			}
+		default:
+			stdout.Write(res)
+		}
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || file != path && filepath.Ext(file) != sourceExt {
+				return nil
+			}
+
+			changed, err := fmtFile(file, *write, *list || *check, stdout)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				status = 2
+			} else if changed && *check && status == 0 {
+				status = 1
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey fmt: %s\n", err)
+			status = 2
+		}
+	}
+
+	return status
+}
+
+// fmtFile formats the file at path, writing it back if write is set,
+// listing its path if list is, and printing the result otherwise.
+// It reports whether the file's formatting differed. Errors, including
+// syntax errors, are prefixed by the path.
+func fmtFile(path string, write, list bool, stdout io.Writer) (bool, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := format.Source(src)
+	if err != nil {
+		return false, fmt.Errorf("%s:%w", path, err)
+	}
+
+	changed := !bytes.Equal(src, res)
+	if list && changed {
+		fmt.Fprintln(stdout, path)
+	}
+
+	if write {
+		if changed {
+			info, err := os.Stat(path)
+			if err != nil {
+				return false, err
+			}
+			if err := os.WriteFile(path, res, info.Mode().Perm()); err != nil {
+				return false, err
+			}
+		}
+	} else if !list {
+		stdout.Write(res)
+	}
+
+	return changed, nil
+}
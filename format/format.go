@@ -0,0 +1,82 @@
+// Package format implements canonical formatting of Monkey source code.
+// It prints an AST back out as re-parseable source with consistent
+// indentation and spacing, only parenthesizing where precedence calls
+// for it, and keeps the comments and blank lines of the original.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/adamwoolhether/monkeyLang/ast"
+	"github.com/adamwoolhether/monkeyLang/lexer"
+	"github.com/adamwoolhether/monkeyLang/parser"
+)
+
+// SyntaxError is returned by Source when the source doesn't parse.
+type SyntaxError struct {
+	Errors []*parser.ParseError
+}
+
+// Error returns the first of the errors, noting how many more there are.
+func (e *SyntaxError) Error() string {
+	msg := e.Errors[0].Error()
+	if n := len(e.Errors) - 1; n > 0 {
+		msg += fmt.Sprintf(" (and %d more errors)", n)
+	}
+
+	return msg
+}
+
+// Source formats src in canonical Monkey style, returning a
+// *SyntaxError if it isn't a valid Monkey program.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	l.EmitComments(true)
+
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &SyntaxError{Errors: p.Errors()}
+	}
+
+	var buf bytes.Buffer
+	if err := Node(&buf, program); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Node writes the canonical source for node to w. Node may be a
+// *ast.Program, whose comments are printed along with it, or any
+// statement or expression. Programs end with a newline.
+func Node(w io.Writer, node ast.Node) error {
+	p := &printer{lineStart: true}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		p.comments = node.Comments
+		p.stmtList(node.Statements, endOfInput)
+		if p.buf.Len() > 0 {
+			p.newline()
+		}
+	case ast.Statement:
+		p.stmt(node)
+		if es, ok := node.(*ast.ExpressionStatement); ok {
+			p.exprStmtEnd(es, nil)
+		}
+	case ast.Expression:
+		p.expr(node, parser.LOWEST)
+	default:
+		return fmt.Errorf("format: unsupported node %T", node)
+	}
+
+	if p.err != nil {
+		return p.err
+	}
+
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
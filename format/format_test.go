@@ -0,0 +1,287 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/adamwoolhether/monkeyLang/ast"
+	"github.com/adamwoolhether/monkeyLang/lexer"
+	"github.com/adamwoolhether/monkeyLang/parser"
+	"github.com/adamwoolhether/monkeyLang/token"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let   x=1+2*3", "let x = 1 + 2 * 3;\n"},
+		{"((1 + 2)) * 3;", "(1 + 2) * 3;\n"},
+		{"a - (b - c); (a - b) - c;", "a - (b - c);\na - b - c;\n"},
+		{"2 ** (3 ** 2); (2 ** 3) ** 2;", "2 ** 3 ** 2;\n(2 ** 3) ** 2;\n"},
+		{"-(2 ** 2); (-2) ** 2; 2 ** -1;", "-2 ** 2;\n(-2) ** 2;\n2 ** -1;\n"},
+		{"-(f(1)); (-f)(1); (a + b)[0];", "-f(1);\n(-f)(1);\n(a + b)[0];\n"},
+		{"a = (b = 1); x + (y = 2);", "a = b = 1;\nx + (y = 2);\n"},
+		{"!(a == b) && (c || d)", "!(a == b) && (c || d);\n"},
+		{"0xFF + 1_000 + 1.5e3", "0xFF + 1_000 + 1.5e3;\n"},
+		{"`raw\\n` + \"tab\\t\\\"q\\\"\\u{7}\"", "\"raw\\\\n\" + \"tab\\t\\\"q\\\"\\u{7}\";\n"},
+		{"let add = fn(a,b){a+b};", "let add = fn(a, b) { a + b };\n"},
+		{"let add = fn(a,b){\nreturn a+b;}", "let add = fn(a, b) {\n  return a + b;\n};\n"},
+		{"let f = fn(a, b=2, ...rest) {}", "let f = fn(a, b = 2, ...rest) {};\n"},
+		{"let f = fn(...rest) { rest }", "let f = fn(...rest) { rest };\n"},
+		{"const [a,...b] = xs; let {name,age} = person", "const [a, ...b] = xs;\nlet {name, age} = person;\n"},
+		{
+			"if (x) { 1 } else {\n2 }",
+			"if (x) {\n  1;\n} else {\n  2;\n}\n",
+		},
+		{"if (x) { 1 } else { 2 }", "if (x) { 1 } else { 2 }\n"},
+		{
+			"if (x) { 1 }; -1; if (y) { 2 } puts(y);",
+			"if (x) { 1 };\n-1;\nif (y) { 2 }\nputs(y);\n",
+		},
+		{
+			"while (i < 3) { i += 1; if (i == 2) { continue; } }",
+			"while (i < 3) {\n  i += 1;\n  if (i == 2) { continue; }\n}\n",
+		},
+		{
+			"for (k, v in h) { puts(k, v) } for (v in xs) {\nbreak }",
+			"for (k, v in h) { puts(k, v) }\nfor (v in xs) {\n  break;\n}\n",
+		},
+		{
+			"try { throw \"bad\" } catch (e) { puts(e) } finally { done() }",
+			"try { throw \"bad\"; } catch (e) { puts(e) } finally { done() }\n",
+		},
+		{
+			"let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) };",
+			"let unless = macro(c, a) { quote(if (!unquote(c)) { unquote(a) }) };\n",
+		},
+		{"f(...xs, {\"a\": 1, \"b\": [1, 2]})", "f(...xs, {\"a\": 1, \"b\": [1, 2]});\n"},
+		{
+			"let h = {\n\"one\": 1, \"two\": 2\n};",
+			"let h = {\n  \"one\": 1,\n  \"two\": 2\n};\n",
+		},
+	}
+
+	for _, tt := range tests {
+		testSource(t, tt.input, tt.expected)
+	}
+}
+
+func TestSourceLayout(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;",
+			"let a = 1;\n\nlet b = 2;\nlet c = 3;\n",
+		},
+		{
+			"let f = fn() {\n\n  let a = 1;\n\n  a\n\n};",
+			"let f = fn() {\n  let a = 1;\n\n  a;\n};\n",
+		},
+		{"\n\n", ""},
+	}
+
+	for _, tt := range tests {
+		testSource(t, tt.input, tt.expected)
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"// just a comment", "// just a comment\n"},
+		{
+			"// add sums its args.\nlet add = fn(a, b) { a + b };   // trailing   \nadd(1, 2)",
+			"// add sums its args.\nlet add = fn(a, b) { a + b }; // trailing\nadd(1, 2);\n",
+		},
+		{
+			"let a = 1; let b = 2; // about b",
+			"let a = 1;\nlet b = 2; // about b\n",
+		},
+		{
+			"let f = fn(x) { x /* inline */ };",
+			"let f = fn(x) {\n  x; /* inline */\n};\n",
+		},
+		{
+			"if (x) {\n  // nothing yet\n}",
+			"if (x) {\n  // nothing yet\n}\n",
+		},
+		{
+			"let f = fn() {\n  a();\n\n  // then b\n  b();\n  // the end\n};",
+			"let f = fn() {\n  a();\n\n  // then b\n  b();\n  // the end\n};\n",
+		},
+		{
+			"let xs = [\n  1, // one\n  // two comes next\n  2\n];",
+			"let xs = [\n  1, // one\n  // two comes next\n  2\n];\n",
+		},
+		{
+			"f(a /* first */, b); g();",
+			"f(a, b); /* first */\ng();\n",
+		},
+		{"/* a */ /* b */\nx", "/* a */\n/* b */\nx;\n"},
+	}
+
+	for _, tt := range tests {
+		testSource(t, tt.input, tt.expected)
+	}
+}
+
+func testSource(t *testing.T, input, expected string) {
+	t.Helper()
+
+	out, err := Source([]byte(input))
+	if err != nil {
+		t.Errorf("Source(%q) error: %s", input, err)
+		return
+	}
+	if string(out) != expected {
+		t.Errorf("Source(%q) wrong.\nwant=%q\ngot= %q", input, expected, out)
+		return
+	}
+
+	again, err := Source(out)
+	if err != nil {
+		t.Errorf("formatted source of %q doesn't parse: %s", input, err)
+		return
+	}
+	if !bytes.Equal(again, out) {
+		t.Errorf("formatting %q isn't idempotent.\nfirst= %q\nsecond=%q", input, out, again)
+	}
+
+	if orig, formatted := parse(t, input), parse(t, string(out)); orig != formatted {
+		t.Errorf("formatting %q changed its meaning.\nwant=%q\ngot= %q", input, orig, formatted)
+	}
+}
+
+// parse returns the String() of the program parsed from input, which
+// spells out its structure. Hashes with several pairs render in random
+// order, so the tests here that compare it keep to one pair each.
+func parse(t *testing.T, input string) string {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	if hasMultiPairHash(program) {
+		return ""
+	}
+	return program.String()
+}
+
+func hasMultiPairHash(node ast.Node) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if h, ok := n.(*ast.HashLiteral); ok && len(h.Pairs) > 1 {
+			found = true
+		}
+		return !found
+	})
+
+	return found
+}
+
+func TestSourceSyntaxError(t *testing.T) {
+	_, err := Source([]byte("let x = ;\nlet = 1;"))
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("err is not *SyntaxError. got=%T (%v)", err, err)
+	}
+	if len(syntaxErr.Errors) != 2 {
+		t.Errorf("wrong number of errors. want=2, got=%d", len(syntaxErr.Errors))
+	}
+
+	expected := "1:9: unexpected ';' (and 1 more errors)"
+	if err.Error() != expected {
+		t.Errorf("wrong error message. want=%q, got=%q", expected, err.Error())
+	}
+}
+
+func TestNode(t *testing.T) {
+	ident := func(name string) *ast.Identifier { return &ast.Identifier{Value: name} }
+	integer := func(value int64) *ast.IntegerLiteral { return &ast.IntegerLiteral{Value: value} }
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{
+			&ast.InfixExpression{
+				Left:     &ast.InfixExpression{Left: ident("a"), Operator: "+", Right: ident("b")},
+				Operator: "*",
+				Right:    &ast.InfixExpression{Left: ident("c"), Operator: "-", Right: ident("d")},
+			},
+			"(a + b) * (c - d)",
+		},
+		{&ast.InfixExpression{Left: integer(-2), Operator: "**", Right: integer(2)}, "(-2) ** 2"},
+		{&ast.InfixExpression{Left: integer(1), Operator: "-", Right: integer(-2)}, "1 - -2"},
+		{&ast.FloatLiteral{Value: 2}, "2.0"},
+		{&ast.FloatLiteral{Value: 1e21}, "1e+21"},
+		{&ast.StringLiteral{Value: "a\nb"}, `"a\nb"`},
+		{&ast.ExpressionStatement{Expression: ident("x")}, "x;"},
+		{
+			&ast.LetStatement{
+				Token: token.Token{Type: token.CONST, Literal: "const"},
+				Name:  ident("f"),
+				Value: &ast.FunctionLiteral{
+					Parameters: []*ast.Identifier{ident("x")},
+					Body: &ast.BlockStatement{Statements: []ast.Statement{
+						&ast.ReturnStatement{ReturnValue: ident("x")},
+					}},
+				},
+			},
+			"const f = fn(x) {\n  return x;\n};",
+		},
+		{
+			&ast.Program{Statements: []ast.Statement{
+				&ast.ExpressionStatement{Expression: &ast.CallExpression{
+					Function:  ident("puts"),
+					Arguments: []ast.Expression{&ast.Boolean{Value: true}},
+				}},
+			}},
+			"puts(true);\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Node(&buf, tt.node); err != nil {
+			t.Errorf("Node(%s) error: %s", tt.node, err)
+			continue
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("Node(%s) wrong. want=%q, got=%q", tt.node, tt.expected, buf.String())
+		}
+	}
+}
+
+func TestNodeErrors(t *testing.T) {
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{&ast.ExpressionStatement{}, "format: missing expression"},
+		{&ast.WhileStatement{Condition: &ast.Boolean{Value: true}}, "format: missing block"},
+		{&ast.Comment{}, "format: unsupported node *ast.Comment"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := Node(&buf, tt.node)
+
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%v", tt.expected, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("output written despite the error: %q", buf.String())
+		}
+	}
+}
@@ -0,0 +1,714 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/adamwoolhether/monkeyLang/ast"
+	"github.com/adamwoolhether/monkeyLang/parser"
+	"github.com/adamwoolhether/monkeyLang/token"
+)
+
+// indentation is printed once per level of nesting.
+const indentation = "  "
+
+// endOfInput is an offset past every comment in the source.
+const endOfInput = math.MaxInt
+
+// primary is the precedence of expressions that never need parentheses,
+// as they either are a single token or are delimited by brackets.
+const primary = parser.INDEX + 1
+
+// printer accumulates the formatted source of an AST. Comments aren't
+// part of the AST, so they're printed in between the nodes they were
+// found between, by comparing their offsets to the nodes' positions.
+type printer struct {
+	buf       bytes.Buffer
+	indent    int
+	lineStart bool           // nothing has been printed on the current line yet
+	comments  []*ast.Comment // comments still to be printed, in source order
+	err       error          // the first node that couldn't be printed
+}
+
+func (p *printer) print(strs ...string) {
+	for _, s := range strs {
+		if s == "" {
+			continue
+		}
+		if p.lineStart {
+			p.buf.WriteString(strings.Repeat(indentation, p.indent))
+			p.lineStart = false
+		}
+		p.buf.WriteString(s)
+	}
+}
+
+func (p *printer) newline() {
+	p.buf.WriteByte('\n')
+	p.lineStart = true
+}
+
+// fail records why the AST can't be printed, unless it already failed.
+func (p *printer) fail(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("format: "+format, args...)
+	}
+}
+
+// commentsBefore removes and returns the comments starting before offset.
+func (p *printer) commentsBefore(offset int) []*ast.Comment {
+	n := 0
+	for n < len(p.comments) && p.comments[n].Pos().Offset < offset {
+		n++
+	}
+
+	before := p.comments[:n]
+	p.comments = p.comments[n:]
+	return before
+}
+
+// commentsWithin reports whether any comment starts between from and to.
+func (p *printer) commentsWithin(from, to token.Position) bool {
+	for _, c := range p.comments {
+		if off := c.Pos().Offset; off >= from.Offset && off < to.Offset {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trailingComments prints, on the current line, the comments left inside
+// the node ending at end, followed by those on the same line after it and
+// before limit. It returns the end of the last thing printed.
+func (p *printer) trailingComments(end token.Position, limit int) token.Position {
+	if !end.IsValid() {
+		return end
+	}
+
+	last := end
+	for len(p.comments) > 0 {
+		c := p.comments[0]
+		inside := c.Pos().Offset < end.Offset
+		sameLine := c.Pos().Line == last.Line && c.Pos().Offset < limit
+		if !inside && !sameLine {
+			break
+		}
+
+		p.print(" ", commentText(c))
+		last = c.End()
+		p.comments = p.comments[1:]
+	}
+
+	return last
+}
+
+// commentText returns c's text, without the trailing
+// whitespace a line comment may have picked up.
+func commentText(c *ast.Comment) string {
+	if strings.HasPrefix(c.Token.Literal, "//") {
+		return strings.TrimRight(c.Token.Literal, " \t\r")
+	}
+
+	return c.Token.Literal
+}
+
+// stmtList prints a statement per line, along with the comments up to
+// the offset end. A blank line between two statements is kept, while
+// runs of blank lines are collapsed into one.
+func (p *printer) stmtList(list []ast.Statement, end int) {
+	var prev token.Position
+	started := false
+
+	separate := func(pos token.Position) {
+		if started {
+			p.newline()
+			if prev.IsValid() && pos.IsValid() && pos.Line > prev.Line+1 {
+				p.newline()
+			}
+		}
+		started = true
+	}
+
+	for i, s := range list {
+		for _, c := range p.commentsBefore(s.Pos().Offset) {
+			separate(c.Pos())
+			p.print(commentText(c))
+			prev = c.End()
+		}
+
+		var next ast.Statement
+		limit := end
+		if i+1 < len(list) {
+			next = list[i+1]
+			limit = next.Pos().Offset
+		}
+
+		separate(s.Pos())
+		p.stmt(s)
+		if es, ok := s.(*ast.ExpressionStatement); ok {
+			p.exprStmtEnd(es, next)
+		}
+		prev = p.trailingComments(s.End(), limit)
+	}
+
+	for _, c := range p.commentsBefore(end) {
+		separate(c.Pos())
+		p.print(commentText(c))
+		prev = c.End()
+	}
+}
+
+// exprStmtEnd ends an expression statement with a semicolon, unless
+// it ends with a block. Even then, one is needed if the statement
+// coming next would otherwise be read as continuing the expression.
+func (p *printer) exprStmtEnd(es *ast.ExpressionStatement, next ast.Statement) {
+	if _, ok := ast.Unparen(es.Expression).(*ast.IfExpression); !ok {
+		p.print(";")
+		return
+	}
+
+	if next, ok := next.(*ast.ExpressionStatement); ok {
+		switch leadingChar(next.Expression, parser.LOWEST) {
+		case '(', '[', '-':
+			p.print(";")
+		}
+	}
+}
+
+func (p *printer) stmt(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.ExpressionStatement:
+		p.expr(s.Expression, parser.LOWEST)
+
+	case *ast.LetStatement:
+		if s.Name == nil {
+			p.fail("unsupported node %T", s)
+			return
+		}
+		p.print(bindingKeyword(s.Token), " ", s.Name.Value, " = ")
+		p.expr(s.Value, parser.LOWEST)
+		p.print(";")
+
+	case *ast.DestructuringLetStatement:
+		p.print(bindingKeyword(s.Token), " ")
+		p.pattern(s.Pattern)
+		p.print(" = ")
+		p.expr(s.Value, parser.LOWEST)
+		p.print(";")
+
+	case *ast.ReturnStatement:
+		p.print("return")
+		if s.ReturnValue != nil {
+			p.print(" ")
+			p.expr(s.ReturnValue, parser.LOWEST)
+		}
+		p.print(";")
+
+	case *ast.WhileStatement:
+		p.print("while (")
+		p.expr(s.Condition, parser.LOWEST)
+		p.print(") ")
+		p.block(s.Body)
+
+	case *ast.ForInStatement:
+		p.print("for (")
+		if s.Key != nil {
+			p.print(s.Key.Value, ", ")
+		}
+		if s.Value == nil {
+			p.fail("unsupported node %T", s)
+			return
+		}
+		p.print(s.Value.Value, " in ")
+		p.expr(s.Iterable, parser.LOWEST)
+		p.print(") ")
+		p.block(s.Body)
+
+	case *ast.BreakStatement:
+		p.print("break;")
+
+	case *ast.ContinueStatement:
+		p.print("continue;")
+
+	case *ast.TryStatement:
+		p.print("try ")
+		p.block(s.Block)
+		if s.Catch != nil {
+			p.print(" catch ")
+			if s.Param != nil {
+				p.print("(", s.Param.Value, ") ")
+			}
+			p.block(s.Catch)
+		}
+		if s.Finally != nil {
+			p.print(" finally ")
+			p.block(s.Finally)
+		}
+
+	case *ast.ThrowStatement:
+		p.print("throw ")
+		p.expr(s.Value, parser.LOWEST)
+		p.print(";")
+
+	case *ast.BlockStatement:
+		p.block(s)
+
+	default:
+		p.fail("unsupported node %T", s)
+	}
+}
+
+// bindingKeyword returns "const" for a const statement's token, and "let" otherwise.
+func bindingKeyword(tok token.Token) string {
+	if tok.Type == token.CONST {
+		return "const"
+	}
+
+	return "let"
+}
+
+func (p *printer) pattern(pattern ast.Pattern) {
+	switch pattern := pattern.(type) {
+	case *ast.ArrayPattern:
+		names := []string{}
+		for _, el := range pattern.Elements {
+			names = append(names, el.Value)
+		}
+		if pattern.Rest != nil {
+			names = append(names, "..."+pattern.Rest.Value)
+		}
+		p.print("[", strings.Join(names, ", "), "]")
+
+	case *ast.HashPattern:
+		keys := []string{}
+		for _, key := range pattern.Keys {
+			keys = append(keys, key.Value)
+		}
+		p.print("{", strings.Join(keys, ", "), "}")
+
+	default:
+		p.fail("unsupported node %T", pattern)
+	}
+}
+
+// block prints b between braces. A block with a single simple statement
+// that was written on one line stays on one line, ex: fn(x) { x * 2 }.
+func (p *printer) block(b *ast.BlockStatement) {
+	p.blockInline(b, p.fitsOnOneLine(b))
+}
+
+// fitsOnOneLine reports whether b may be printed on a single line.
+func (p *printer) fitsOnOneLine(b *ast.BlockStatement) bool {
+	return b != nil && len(b.Statements) == 1 && isSimple(b.Statements[0]) &&
+		oneLine(b.Token.Pos, b.Rbrace.Pos) && !p.commentsWithin(b.Token.Pos, b.Rbrace.Pos)
+}
+
+// blockInline prints b between braces, on one line if inline is set.
+func (p *printer) blockInline(b *ast.BlockStatement, inline bool) {
+	if b == nil {
+		p.fail("missing block")
+		return
+	}
+
+	if len(b.Statements) == 0 && !p.commentsWithin(b.Token.Pos, b.Rbrace.Pos) {
+		p.print("{}")
+		return
+	}
+
+	if inline {
+		p.print("{ ")
+		p.stmt(b.Statements[0])
+		p.print(" }")
+		return
+	}
+
+	p.print("{")
+	p.indent++
+	p.newline()
+	p.stmtList(b.Statements, b.Rbrace.Pos.Offset)
+	p.indent--
+	p.newline()
+	p.print("}")
+}
+
+// oneLine reports whether the source from pos to end was written on a single line.
+func oneLine(pos, end token.Position) bool {
+	return pos.IsValid() && end.IsValid() && pos.Line == end.Line
+}
+
+// isSimple reports whether s may share a line with the braces around it.
+func isSimple(s ast.Statement) bool {
+	switch s.(type) {
+	case *ast.ExpressionStatement, *ast.ReturnStatement, *ast.ThrowStatement,
+		*ast.BreakStatement, *ast.ContinueStatement:
+		return true
+	}
+
+	return false
+}
+
+// precedence returns how tightly the printed form of e binds, in terms
+// of the parser's precedences.
+func precedence(e ast.Expression) int {
+	switch e := ast.Unparen(e).(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(token.TokenType(e.Operator))
+	case *ast.AssignExpression:
+		return parser.ASSIGN
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression:
+		return parser.INDEX
+	case *ast.IntegerLiteral:
+		if strings.HasPrefix(integerText(e), "-") {
+			return parser.PREFIX
+		}
+	case *ast.FloatLiteral:
+		if strings.HasPrefix(floatText(e), "-") {
+			return parser.PREFIX
+		}
+	}
+
+	return primary
+}
+
+// operandPrecedences returns the precedences the left and right operands
+// of an infix operator of the given precedence need to be printed without
+// parentheses. '**' is right-associative, the other operators left.
+func operandPrecedences(operator string, prec int) (left, right int) {
+	if operator == "**" {
+		return prec + 1, prec
+	}
+
+	return prec, prec + 1
+}
+
+// expr prints e, wrapped in parentheses if it binds less tightly than prec.
+func (p *printer) expr(e ast.Expression, prec int) {
+	if e == nil {
+		p.fail("missing expression")
+		return
+	}
+
+	// The parentheses in the source are left out,
+	// as precedence decides which ones are needed.
+	if pe, ok := e.(*ast.ParenExpression); ok {
+		p.expr(pe.Expression, prec)
+		return
+	}
+
+	if precedence(e) < prec {
+		p.print("(")
+		p.expr(e, parser.LOWEST)
+		p.print(")")
+		return
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.print(e.Value)
+
+	case *ast.IntegerLiteral:
+		p.print(integerText(e))
+
+	case *ast.FloatLiteral:
+		p.print(floatText(e))
+
+	case *ast.Boolean:
+		p.print(strconv.FormatBool(e.Value))
+
+	case *ast.StringLiteral:
+		p.print(quote(e.Value))
+
+	case *ast.PrefixExpression:
+		p.print(e.Operator)
+		p.expr(e.Right, parser.PREFIX)
+
+	case *ast.InfixExpression:
+		left, right := operandPrecedences(e.Operator, precedence(e))
+		p.expr(e.Left, left)
+		p.print(" ", e.Operator, " ")
+		p.operand(e.Right, right)
+
+	case *ast.AssignExpression:
+		p.expr(e.Target, parser.CALL)
+		p.print(" ", e.Operator, " ")
+		p.operand(e.Value, parser.ASSIGN)
+
+	case *ast.IfExpression:
+		p.print("if (")
+		p.expr(e.Condition, parser.LOWEST)
+		p.print(") ")
+
+		// Both branches go on one line, or neither does.
+		inline := p.fitsOnOneLine(e.Consequence)
+		if e.Alternative != nil {
+			inline = inline && p.fitsOnOneLine(e.Alternative)
+		}
+
+		p.blockInline(e.Consequence, inline)
+		if e.Alternative != nil {
+			p.print(" else ")
+			p.blockInline(e.Alternative, inline)
+		}
+
+	case *ast.FunctionLiteral:
+		p.print("fn(")
+		for i, param := range e.Parameters {
+			if i > 0 {
+				p.print(", ")
+			}
+			p.print(param.Value)
+			if def, ok := e.Defaults[param.Value]; ok {
+				p.print(" = ")
+				p.expr(def, parser.LOWEST)
+			}
+		}
+		if e.Rest != nil {
+			if len(e.Parameters) > 0 {
+				p.print(", ")
+			}
+			p.print("...", e.Rest.Value)
+		}
+		p.print(") ")
+		p.block(e.Body)
+
+	case *ast.MacroLiteral:
+		params := []string{}
+		for _, param := range e.Parameters {
+			params = append(params, param.Value)
+		}
+		p.print("macro(", strings.Join(params, ", "), ") ")
+		p.block(e.Body)
+
+	case *ast.CallExpression:
+		p.expr(e.Function, parser.CALL)
+		p.print("(")
+		p.exprList(e.Arguments)
+		p.print(")")
+
+	case *ast.SpreadExpression:
+		p.print("...")
+		p.expr(e.Value, parser.LOWEST)
+
+	case *ast.IndexExpression:
+		p.expr(e.Left, parser.CALL)
+		p.print("[")
+		p.expr(e.Index, parser.LOWEST)
+		p.print("]")
+
+	case *ast.ArrayLiteral:
+		p.arrayLiteral(e)
+
+	case *ast.HashLiteral:
+		p.hashLiteral(e)
+
+	default:
+		p.fail("unsupported node %T", e)
+	}
+}
+
+// operand prints the right operand of an operator. A prefix expression
+// there needs no parentheses, whatever the operator: nothing else can
+// start where it does, so it can't be mistaken for the operator's left
+// operand, ex: 2 ** -1.
+func (p *printer) operand(e ast.Expression, prec int) {
+	if precedence(e) == parser.PREFIX {
+		prec = parser.PREFIX
+	}
+
+	p.expr(e, prec)
+}
+
+// leadingChar returns the first char e is printed with when it needs
+// to bind at least as tightly as prec, or 0 if that's a letter, digit
+// or quote, which can't continue an expression that came before.
+func leadingChar(e ast.Expression, prec int) byte {
+	if e == nil {
+		return 0
+	}
+	if precedence(e) < prec {
+		return '('
+	}
+
+	switch e := ast.Unparen(e).(type) {
+	case *ast.InfixExpression:
+		left, _ := operandPrecedences(e.Operator, precedence(e))
+		return leadingChar(e.Left, left)
+	case *ast.AssignExpression:
+		return leadingChar(e.Target, parser.CALL)
+	case *ast.CallExpression:
+		return leadingChar(e.Function, parser.CALL)
+	case *ast.IndexExpression:
+		return leadingChar(e.Left, parser.CALL)
+	case *ast.PrefixExpression:
+		return e.Operator[0]
+	case *ast.IntegerLiteral, *ast.FloatLiteral:
+		if precedence(e) == parser.PREFIX {
+			return '-'
+		}
+	case *ast.ArrayLiteral:
+		return '['
+	case *ast.HashLiteral:
+		return '{'
+	}
+
+	return 0
+}
+
+func (p *printer) exprList(exps []ast.Expression) {
+	for i, e := range exps {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.expr(e, parser.LOWEST)
+	}
+}
+
+// span is the source extent of an item in a list.
+type span struct {
+	pos, end token.Position
+}
+
+// lines prints the items of a list one per line, separated by commas,
+// along with the comments up to end. print prints the i'th item.
+func (p *printer) lines(items []span, end token.Position, print func(i int)) {
+	p.indent++
+
+	for i, item := range items {
+		for _, c := range p.commentsBefore(item.pos.Offset) {
+			p.newline()
+			p.print(commentText(c))
+		}
+
+		p.newline()
+		print(i)
+
+		limit := end.Offset
+		if i+1 < len(items) {
+			p.print(",")
+			limit = items[i+1].pos.Offset
+		}
+		p.trailingComments(item.end, limit)
+	}
+
+	for _, c := range p.commentsBefore(end.Offset) {
+		p.newline()
+		p.print(commentText(c))
+	}
+
+	p.indent--
+	p.newline()
+}
+
+// arrayLiteral prints al on one line, unless it spanned several in the source.
+func (p *printer) arrayLiteral(al *ast.ArrayLiteral) {
+	p.print("[")
+
+	if len(al.Elements) == 0 || !al.Token.Pos.IsValid() || oneLine(al.Token.Pos, al.Rbracket.Pos) {
+		p.exprList(al.Elements)
+	} else {
+		items := make([]span, len(al.Elements))
+		for i, el := range al.Elements {
+			items[i] = span{el.Pos(), el.End()}
+		}
+		p.lines(items, al.Rbracket.Pos, func(i int) {
+			p.expr(al.Elements[i], parser.LOWEST)
+		})
+	}
+
+	p.print("]")
+}
+
+// hashLiteral prints hl's pairs in source order, on one line
+// unless it spanned several in the source.
+func (p *printer) hashLiteral(hl *ast.HashLiteral) {
+	keys := hl.Keys()
+	pair := func(i int) {
+		p.expr(keys[i], parser.LOWEST)
+		p.print(": ")
+		p.expr(hl.Pairs[keys[i]], parser.LOWEST)
+	}
+
+	p.print("{")
+
+	if len(keys) == 0 || !hl.Token.Pos.IsValid() || oneLine(hl.Token.Pos, hl.Rbrace.Pos) {
+		for i := range keys {
+			if i > 0 {
+				p.print(", ")
+			}
+			pair(i)
+		}
+	} else {
+		items := make([]span, len(keys))
+		for i, key := range keys {
+			items[i] = span{key.Pos(), hl.Pairs[key].End()}
+		}
+		p.lines(items, hl.Rbrace.Pos, pair)
+	}
+
+	p.print("}")
+}
+
+// integerText returns the literal il was written as, so that
+// ex: 0xff stays as it is, or its value if it wasn't parsed.
+func integerText(il *ast.IntegerLiteral) string {
+	if il.Token.Type == token.INT && il.Token.Literal != "" {
+		return il.Token.Literal
+	}
+
+	return strconv.FormatInt(il.Value, 10)
+}
+
+// floatText returns the literal fl was written as, or its value if it
+// wasn't parsed, in a form that still reads as a float.
+func floatText(fl *ast.FloatLiteral) string {
+	if fl.Token.Type == token.FLOAT && fl.Token.Literal != "" {
+		return fl.Token.Literal
+	}
+
+	text := strconv.FormatFloat(fl.Value, 'g', -1, 64)
+	if !strings.ContainsAny(text, ".eIN") {
+		text += ".0"
+	}
+
+	return text
+}
+
+// quote returns s as a double-quoted string literal, escaping the
+// chars that need it and those that wouldn't be visible otherwise.
+func quote(s string) string {
+	var out strings.Builder
+
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if unicode.IsPrint(r) {
+				out.WriteRune(r)
+			} else {
+				fmt.Fprintf(&out, `\u{%x}`, r)
+			}
+		}
+	}
+	out.WriteByte('"')
+
+	return out.String()
+}
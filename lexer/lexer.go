@@ -1,40 +1,103 @@
 // Package lexer takes source code as input and outputs the tokens that represent the source code.
 package lexer
 
-import "github.com/adamwoolhether/monkeyLang/token"
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	
+	"github.com/adamwoolhether/monkeyLang/token"
+)
 
 // Lexer contains the inputted source code and defines methods
 // to obtain information about the input's characters.
 type Lexer struct {
 	input        string
+	filename     string
 	position     int  // current position in input (points to the current char)
 	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
+	ch           rune // current char under examination
+	line         int  // line of the current char
+	column       int  // column of the current char, counted in runes
+	
+	emitComments bool // return comments as token.COMMENT rather than skipping them
+	errors       []Error
+}
+
+// Error describes a malformed token. The lexer emits a token.ILLEGAL
+// token for it, and records the reason, keyed by the token's position.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+// Errors returns the errors encountered so far, in source order.
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
+
+// ErrorAt returns the message recorded for the ILLEGAL
+// token starting at pos, if the lexer recorded one.
+func (l *Lexer) ErrorAt(pos token.Position) (string, bool) {
+	for _, e := range l.errors {
+		if e.Pos.Offset == pos.Offset {
+			return e.Msg, true
+		}
+	}
+	
+	return "", false
+}
+
+// addError records a lexical error for the token starting at pos.
+func (l *Lexer) addError(pos token.Position, format string, a ...interface{}) {
+	l.errors = append(l.errors, Error{Pos: pos, Msg: fmt.Sprintf(format, a...)})
 }
 
 // New returns a new Lexer with l.ch, l.position, and l.readPosition already initialized.
 func New(input string) *Lexer {
+	return NewWithFilename("", input)
+}
+
+// NewWithFilename returns a new Lexer whose token positions
+// are reported as belonging to the given file.
+func NewWithFilename(filename, input string) *Lexer {
 	l := &Lexer{
-		input: input,
+		input:    input,
+		filename: filename,
+		line:     1,
 	}
 	l.readChar()
 	
 	return l
 }
 
+// EmitComments controls whether NextToken returns comments as token.COMMENT
+// tokens. By default comments are skipped like whitespace, as the parser has
+// no use for them, but tools that rewrite source need to preserve them.
+func (l *Lexer) EmitComments(emit bool) {
+	l.emitComments = emit
+}
+
 // readChar gives the next character and advances to the next position in the input string.
-// If the end of input is reached, ch is set to the ASCII code for "NUL", 0.
-// Currently only ASCII chars are supported. Unicode & UTF-8 support require conversion of
-// l.ch from a byte to a rune, as well as changing how the next char is read, as it could
-// be multiple bytes. // TODO: Implement full Unicode support for Monkey.
+// If the end of input is reached, ch is set to the ASCII code for "NUL", 0. The input is
+// decoded as UTF-8, so a single character may span several bytes. Invalid encodings are
+// read as utf8.RuneError, one byte at a time.
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+	
+	size := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, size = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition++
+	l.readPosition += size
 }
 
 // NextToken determines which token corresponds to the character
@@ -44,6 +107,8 @@ func (l *Lexer) NextToken() token.Token {
 	
 	l.skipWhitespace()
 	
+	pos := l.currentPosition()
+	
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -55,9 +120,17 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.PLUS_ASSIGN)
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.MINUS_ASSIGN)
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -68,13 +141,63 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		if next := l.peekChar(); next == '/' || next == '*' {
+			tok = l.readComment(pos)
+			if tok.Type == token.COMMENT && !l.emitComments {
+				return l.NextToken()
+			}
+			return tok
+		}
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.SLASH_ASSIGN)
+		} else {
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		switch l.peekChar() {
+		case '*':
+			tok = l.readTwoCharToken(token.POWER)
+		case '=':
+			tok = l.readTwoCharToken(token.ASTERISK_ASSIGN)
+		default:
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.readTwoCharToken(token.LT_EQ)
+		case '<':
+			tok = l.readTwoCharToken(token.LSHIFT)
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.readTwoCharToken(token.GT_EQ)
+		case '>':
+			tok = l.readTwoCharToken(token.RSHIFT)
+		default:
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.readTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.AMPERSAND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.readTwoCharToken(token.OR)
+		} else {
+			tok = newToken(token.PIPE, l.ch)
+		}
+	case '^':
+		tok = newToken(token.CARET, l.ch)
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ',':
@@ -89,13 +212,34 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACE, l.ch)
 	case '"':
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		if lit, ok := l.readString(pos); ok {
+			tok.Literal = lit
+		} else {
+			tok.Type = token.ILLEGAL
+			tok.Literal = l.sourceFrom(pos.Offset)
+		}
+	case '`':
+		tok.Type = token.STRING
+		if lit, ok := l.readRawString(pos); ok {
+			tok.Literal = lit
+		} else {
+			tok.Type = token.ILLEGAL
+			tok.Literal = l.sourceFrom(pos.Offset)
+		}
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -103,10 +247,11 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos, tok.End = pos, l.currentPosition()
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Pos, tok.End = pos, l.currentPosition()
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -114,11 +259,31 @@ func (l *Lexer) NextToken() token.Token {
 	}
 	
 	l.readChar()
+	tok.Pos, tok.End = pos, l.currentPosition()
 	return tok
 }
 
+// currentPosition returns the source position of l.ch.
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
+// readTwoCharToken reads the next char and returns a token of the given
+// type whose literal is made up of the current char and the next one.
+func (l *Lexer) readTwoCharToken(tokenType token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	
+	return token.Token{Type: tokenType, Literal: string(ch) + string(l.ch)}
+}
+
 // newToken initializes a token.Token based on the given type.
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{
 		Type:    tokenType,
 		Literal: string(ch),
@@ -136,12 +301,13 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-// isLetter checks whether the given argument is a letter or not. It allows
-// the char '_' to be treated as a letter, allowing it to be used in
-// identifiers and keywords, ex: foo_bar.
+// isLetter checks whether the given argument is a letter or not. Any Unicode
+// letter is accepted, and the char '_' is treated as a letter, allowing it to
+// be used in identifiers and keywords, ex: foo_bar, größe.
 // To allow other identifiers like ! or ?, add them here.
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
 // skipWhitespace skips over whitespace, as Monkey does give them meaning.
@@ -151,21 +317,80 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-// readNumber reads an int from the char at the position and
-// advances the lexer's position until a non-int is encountered.
-// It only supports ints, and not floats, hex, or ocatal notions
-// for the sake of simplicity.
-func (l *Lexer) readNumber() string {
+// readNumber reads a number from the char at the position and advances
+// the lexer's position until the number ends, returning its token type.
+// A number with a fractional part or an exponent, ex: 1.5, 2e10, 1.5e-3,
+// is a float. A '.' or 'e' is only taken as part of the number when it's
+// followed by a digit, so 1.foo still lexes as 1 followed by .foo.
+// Integers may have a 0x, 0o or 0b prefix, in which case any letters
+// and digits that follow are read, leaving it to the parser to report
+// invalid ones. Digits may be separated by '_', ex: 1_000_000.
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position
-	for isDigit(l.ch) {
+	tokenType := token.TokenType(token.INT)
+	
+	if l.ch == '0' && isBasePrefix(l.peekChar()) {
+		l.readChar()
+		l.readChar()
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+		
+		return tokenType, l.input[position:l.position]
+	}
+	
+	l.readDigits()
+	
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
 		l.readChar()
+		l.readDigits()
 	}
 	
-	return l.input[position:l.position]
+	if (l.ch == 'e' || l.ch == 'E') && l.exponentFollows() {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+	
+	return tokenType, l.input[position:l.position]
 }
 
-// isDigit checks whether the passed byte is a digit between 0 and 9.
-func isDigit(ch byte) bool {
+// readDigits advances the lexer's position past a
+// run of digits, including any '_' separators.
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) || l.ch == '_' {
+		l.readChar()
+	}
+}
+
+// isBasePrefix checks whether the char following
+// a '0' makes it a hex, octal or binary prefix.
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	default:
+		return false
+	}
+}
+
+// exponentFollows reports whether the 'e' under examination begins
+// an exponent, that is, it's followed by digits with an optional sign.
+func (l *Lexer) exponentFollows() bool {
+	rest := l.input[l.readPosition:]
+	if len(rest) > 0 && (rest[0] == '+' || rest[0] == '-') {
+		rest = rest[1:]
+	}
+	
+	return len(rest) > 0 && isDigit(rune(rest[0]))
+}
+
+// isDigit checks whether the passed char is a digit between 0 and 9.
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
@@ -173,24 +398,181 @@ func isDigit(ch byte) bool {
 // It simply returns the next character, which is necessary for the lexer
 // to make decisions when a token is composed of two characters. Complex
 // languages require peeking further ahead, and sometimes backwards.
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
-	} else {
-		return l.input[l.readPosition]
 	}
+	
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
 }
 
-// readString calls readChar until a closing double quote or EOF is encountered.
-// It currently doesn't support character escaping of double quotes, newlines, tabs, etc.
-func (l *Lexer) readString() string {
-	position := l.position + 1
+// sourceFrom returns the input from offset up to and including l.ch.
+func (l *Lexer) sourceFrom(offset int) string {
+	end := l.readPosition
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+	
+	return l.input[offset:end]
+}
+
+// atEOF reports whether the lexer has consumed all of its input.
+func (l *Lexer) atEOF() bool {
+	return l.position >= len(l.input)
+}
+
+// readString reads a double-quoted string literal starting at pos, calling
+// readChar until the closing quote and decoding escape sequences on the way:
+// \n, \t, \r, \\, \" and \u{XXXX} for any Unicode code point. String
+// literals may span lines. It returns false if the literal is malformed,
+// recording the first problem found, or isn't closed before EOF.
+func (l *Lexer) readString(pos token.Position) (string, bool) {
+	var out strings.Builder
+	valid := true
+	
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		
+		switch {
+		case l.atEOF():
+			l.addError(pos, "unterminated string literal")
+			return "", false
+		case l.ch == '"':
+			return out.String(), valid
+		case l.ch == '\\':
+			l.readChar()
+			if l.atEOF() {
+				continue
+			}
+			
+			if r, ok := l.readEscape(); ok {
+				out.WriteRune(r)
+			} else if valid {
+				valid = false
+				l.addError(pos, "invalid escape sequence in string literal: %s", l.input[l.escapeStart():l.readPosition])
+			}
+		default:
+			out.WriteRune(l.ch)
 		}
 	}
+}
+
+// readEscape decodes the escape sequence whose first char, following the
+// backslash, is l.ch, leaving l.ch on the sequence's last char.
+func (l *Lexer) readEscape() (rune, bool) {
+	switch l.ch {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case 'r':
+		return '\r', true
+	case '\\':
+		return '\\', true
+	case '"':
+		return '"', true
+	case 'u':
+		if l.peekChar() != '{' {
+			return 0, false
+		}
+		l.readChar()
+		
+		var value rune
+		digits := 0
+		for l.peekChar() != '}' {
+			if !isHexDigit(l.peekChar()) || digits == 6 {
+				return 0, false
+			}
+			l.readChar()
+			value = value<<4 | hexValue(l.ch)
+			digits++
+		}
+		l.readChar()
+		
+		if digits == 0 || !utf8.ValidRune(value) {
+			return 0, false
+		}
+		
+		return value, true
+	default:
+		return 0, false
+	}
+}
+
+// escapeStart returns the offset of the backslash that begins
+// the escape sequence currently being read.
+func (l *Lexer) escapeStart() int {
+	return strings.LastIndexByte(l.input[:l.readPosition], '\\')
+}
+
+// readRawString reads a backtick-delimited raw string literal starting
+// at pos. Raw strings may span lines and no escape sequences are
+// interpreted within them.
+func (l *Lexer) readRawString(pos token.Position) (string, bool) {
+	start := l.position + 1
 	
-	return l.input[position:l.position]
+	for {
+		l.readChar()
+		
+		if l.atEOF() {
+			l.addError(pos, "unterminated raw string literal")
+			return "", false
+		}
+		if l.ch == '`' {
+			return l.input[start:l.position], true
+		}
+	}
+}
+
+// readComment reads a // line comment or a /* */ block comment starting at
+// pos, leaving l.ch on the char following it. The token's literal holds the
+// comment's full text, delimiters included. Line comments run up to, but not
+// including, the next newline. Block comments don't nest, and one that isn't
+// closed before EOF is reported as an error and returned as token.ILLEGAL.
+func (l *Lexer) readComment(pos token.Position) token.Token {
+	tok := token.Token{Type: token.COMMENT, Pos: pos}
+	
+	l.readChar()
+	if l.ch == '/' {
+		for l.ch != '\n' && !l.atEOF() {
+			l.readChar()
+		}
+	} else {
+		for {
+			l.readChar()
+			
+			if l.atEOF() {
+				l.addError(pos, "unterminated block comment")
+				tok.Type = token.ILLEGAL
+				break
+			}
+			if l.ch == '*' && l.peekChar() == '/' {
+				l.readChar()
+				l.readChar()
+				break
+			}
+		}
+	}
+	
+	tok.Literal = l.input[pos.Offset:l.position]
+	tok.End = l.currentPosition()
+	return tok
+}
+
+// isHexDigit checks whether the passed char is a hexadecimal digit.
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+// hexValue returns the numeric value of a hexadecimal digit.
+func hexValue(ch rune) rune {
+	switch {
+	case isDigit(ch):
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
 }
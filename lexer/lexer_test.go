@@ -15,7 +15,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -147,3 +147,336 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  add(x,
+	10);`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedPos    token.Position
+		expectedEndCol int
+	}{
+		{token.LET, token.Position{Filename: "test.mk", Offset: 0, Line: 1, Column: 1}, 4},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 4, Line: 1, Column: 5}, 6},
+		{token.ASSIGN, token.Position{Filename: "test.mk", Offset: 6, Line: 1, Column: 7}, 8},
+		{token.INT, token.Position{Filename: "test.mk", Offset: 8, Line: 1, Column: 9}, 10},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Offset: 9, Line: 1, Column: 10}, 11},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 13, Line: 2, Column: 3}, 6},
+		{token.LPAREN, token.Position{Filename: "test.mk", Offset: 16, Line: 2, Column: 6}, 7},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 17, Line: 2, Column: 7}, 8},
+		{token.COMMA, token.Position{Filename: "test.mk", Offset: 18, Line: 2, Column: 8}, 9},
+		{token.INT, token.Position{Filename: "test.mk", Offset: 21, Line: 3, Column: 2}, 4},
+		{token.RPAREN, token.Position{Filename: "test.mk", Offset: 23, Line: 3, Column: 4}, 5},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Offset: 24, Line: 3, Column: 5}, 6},
+		{token.EOF, token.Position{Filename: "test.mk", Offset: 25, Line: 3, Column: 6}, 7},
+	}
+
+	l := NewWithFilename("test.mk", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%+v, got=%+v", i, tt.expectedPos, tok.Pos)
+		}
+
+		if tok.End.Line != tt.expectedPos.Line || tok.End.Column != tt.expectedEndCol {
+			t.Fatalf("tests[%d] - end position wrong. expected=%d:%d, got=%s",
+				i, tt.expectedPos.Line, tt.expectedEndCol, tok.End)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := `let größe = "日本語";
+π + größe;
+§`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "größe", 5},
+		{token.ASSIGN, "=", 11},
+		{token.STRING, "日本語", 13},
+		{token.SEMICOLON, ";", 18},
+		{token.IDENT, "π", 1},
+		{token.PLUS, "+", 3},
+		{token.IDENT, "größe", 5},
+		{token.SEMICOLON, ";", 10},
+		{token.ILLEGAL, "§", 1},
+		{token.EOF, "", 2},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - column wrong. expected=%d, got=%d", i, tt.expectedColumn, tok.Pos.Column)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"a\nb"`, token.STRING, "a\nb"},
+		{`"tab\there"`, token.STRING, "tab\there"},
+		{`"say \"hi\""`, token.STRING, `say "hi"`},
+		{`"back\\slash"`, token.STRING, `back\slash`},
+		{`"\u{48}\u{e9}\u{1F600}"`, token.STRING, "Hé😀"},
+		{"`raw \\n \"text\"`", token.STRING, `raw \n "text"`},
+		{"`line one\nline two`", token.STRING, "line one\nline two"},
+		{`"never closed`, token.ILLEGAL, `"never closed`},
+		{"`never closed", token.ILLEGAL, "`never closed"},
+		{`"bad \q escape"`, token.ILLEGAL, `"bad \q escape"`},
+		{`"\u{110000}"`, token.ILLEGAL, `"\u{110000}"`},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`let s = "abc`, "unterminated string literal"},
+		{"let s = `abc", "unterminated raw string literal"},
+		{`let s = "a\qb";`, `invalid escape sequence in string literal: \q`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+
+		errs := l.Errors()
+		if len(errs) != 1 {
+			t.Fatalf("input %q: wrong number of errors. want=1, got=%d", tt.input, len(errs))
+		}
+
+		if errs[0].Msg != tt.expectedMessage {
+			t.Errorf("input %q: wrong message. want=%q, got=%q", tt.input, tt.expectedMessage, errs[0].Msg)
+		}
+
+		if errs[0].Pos.Column != 9 {
+			t.Errorf("input %q: wrong column. want=9, got=%d", tt.input, errs[0].Pos.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 10 / 2; // trailing
+/* block
+   comment */ x /**/ * 2;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{token.COMMENT, "// leading comment", 1},
+		{token.LET, "let", 2},
+		{token.IDENT, "x", 2},
+		{token.ASSIGN, "=", 2},
+		{token.INT, "10", 2},
+		{token.SLASH, "/", 2},
+		{token.INT, "2", 2},
+		{token.SEMICOLON, ";", 2},
+		{token.COMMENT, "// trailing", 2},
+		{token.COMMENT, "/* block\n   comment */", 3},
+		{token.IDENT, "x", 4},
+		{token.COMMENT, "/**/", 4},
+		{token.ASTERISK, "*", 4},
+		{token.INT, "2", 4},
+		{token.SEMICOLON, ";", 4},
+		{token.EOF, "", 4},
+	}
+
+	l := New(input)
+	l.EmitComments(true)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Line != tt.expectedLine {
+			t.Fatalf("tests[%d] - line wrong. expected=%d, got=%d", i, tt.expectedLine, tok.Pos.Line)
+		}
+	}
+
+	// Comments are skipped by default.
+	l = New(input)
+	for _, tt := range tests {
+		if tt.expectedType == token.COMMENT {
+			continue
+		}
+
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tokentype wrong with comments skipped. expected=%q, got=%q", tt.expectedType, tok.Type)
+		}
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	l := New("let x = 1; /* never closed")
+
+	var tok token.Token
+	for tok = l.NextToken(); tok.Type != token.ILLEGAL && tok.Type != token.EOF; tok = l.NextToken() {
+	}
+
+	if tok.Type != token.ILLEGAL || tok.Literal != "/* never closed" {
+		t.Fatalf("wrong token. want ILLEGAL %q, got %s %q", "/* never closed", tok.Type, tok.Literal)
+	}
+
+	if msg, ok := l.ErrorAt(tok.Pos); !ok || msg != "unterminated block comment" {
+		t.Fatalf("wrong error. got=%q", msg)
+	}
+
+	if next := l.NextToken(); next.Type != token.EOF {
+		t.Fatalf("expected EOF after the comment, got=%q", next.Type)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"42", token.INT, "42"},
+		{"3.14", token.FLOAT, "3.14"},
+		{"1e3", token.FLOAT, "1e3"},
+		{"1E+3", token.FLOAT, "1E+3"},
+		{"2.5e-10", token.FLOAT, "2.5e-10"},
+		{"1.foo", token.INT, "1"},
+		{"1else", token.INT, "1"},
+		{"7e", token.INT, "7"},
+		{"1_000_000", token.INT, "1_000_000"},
+		{"0xFF_ff", token.INT, "0xFF_ff"},
+		{"0o755", token.INT, "0o755"},
+		{"0b1012", token.INT, "0b1012"},
+		{"0x1.5", token.INT, "0x1"},
+		{"1_000.5", token.FLOAT, "1_000.5"},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := `a <= b >= c && d || e < f > g & h | i ^ ~j % k ** l * m << n >> o += p -= q *= r /= s = t ...u`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.AND, "&&"},
+		{token.IDENT, "d"},
+		{token.OR, "||"},
+		{token.IDENT, "e"},
+		{token.LT, "<"},
+		{token.IDENT, "f"},
+		{token.GT, ">"},
+		{token.IDENT, "g"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "h"},
+		{token.PIPE, "|"},
+		{token.IDENT, "i"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.IDENT, "j"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "k"},
+		{token.POWER, "**"},
+		{token.IDENT, "l"},
+		{token.ASTERISK, "*"},
+		{token.IDENT, "m"},
+		{token.LSHIFT, "<<"},
+		{token.IDENT, "n"},
+		{token.RSHIFT, ">>"},
+		{token.IDENT, "o"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.IDENT, "p"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.IDENT, "q"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.IDENT, "r"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.IDENT, "s"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "t"},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "u"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
@@ -1,20 +1,107 @@
+// Monkey runs Monkey programs, either from script files or interactively.
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"strings"
 	
 	"github.com/adamwoolhether/monkeyLang/repl"
 )
 
+const usage = `Monkey is a tool for running Monkey programs.
+
+usage: monkey <command> [arguments]
+       monkey [-engine vm|eval] <script> [arguments]
+
+The commands are:
+
+	run       run a script
+	repl      start an interactive session, the default without arguments
+	compile   compile a script to bytecode, reporting any errors, without running it
+	disasm    print the bytecode a script compiles to
+	fmt       format Monkey source files
+
+A script given as "-" is read from standard input.
+Run 'monkey <command> -h' for more about a command.
+`
+
+// Exit statuses of the commands other than fmt.
+const (
+	exitOK      = 0
+	exitFailure = 1 // the script failed to parse, compile or run
+	exitUsage   = 2 // bad command line, or the script couldn't be read
+)
+
 func main() {
+	os.Exit(dispatch(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// dispatch runs the command named by args[0], returning its exit status.
+// Anything that isn't a command's name is taken to be a script to run.
+func dispatch(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		return replCommand(args, stdin, stdout, stderr)
+	}
+	
+	switch args[0] {
+	case "run":
+		return runCommand(args[1:], stdin, stdout, stderr)
+	case "repl":
+		return replCommand(args[1:], stdin, stdout, stderr)
+	case "compile":
+		return compileCommand(args[1:], stdin, stdout, stderr)
+	case "disasm":
+		return disasmCommand(args[1:], stdin, stdout, stderr)
+	case "fmt":
+		return fmtCommand(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	}
+	
+	return runCommand(args, stdin, stdout, stderr)
+}
+
+// newFlagSet returns a flag set for the named command, which prints
+// cmdUsage followed by the command's flags when asked for help.
+func newFlagSet(name, cmdUsage string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, cmdUsage)
+		flags.PrintDefaults()
+	}
+	
+	return flags
+}
+
+const replUsage = `usage: monkey repl
+
+Repl starts an interactive session, compiling each line
+as it's entered and printing its value.
+`
+
+func replCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("repl", replUsage, stderr)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintf(stderr, "monkey repl: unexpected arguments: %s\n", strings.Join(flags.Args(), " "))
+		return exitUsage
+	}
+	
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("Hello %s! This is the monkey progrmaming language!\n", user.Username)
-	fmt.Printf("Feel free to type in commands\n")
+	fmt.Fprintf(stdout, "Hello %s! This is the monkey progrmaming language!\n", user.Username)
+	fmt.Fprintf(stdout, "Feel free to type in commands\n")
 	
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(stdin, stdout)
+	return exitOK
 }
@@ -1,6 +1,12 @@
 package object
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
 
 // Builtins defines the builtin functions available to Monkey.
 // We need a guarantee of stable iteration, so a slice is used.
@@ -21,7 +27,7 @@ var Builtins = []struct {
 				case *Array:
 					return &Integer{Value: int64(len(arg.Elements))}
 				case *String:
-					return &Integer{Value: int64(len(arg.Value))}
+					return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 				default:
 					return newError("argument to `len` not supported, got %s",
 						args[0].Type())
@@ -135,6 +141,100 @@ var Builtins = []struct {
 			},
 		},
 	},
+	{
+		"int",
+		&Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1",
+						len(args))
+				}
+
+				switch arg := args[0].(type) {
+				case *Integer:
+					return arg
+				case *Float:
+					if math.IsNaN(arg.Value) || arg.Value < math.MinInt64 || arg.Value >= math.MaxInt64 {
+						return newError("cannot convert %s to INTEGER", arg.Inspect())
+					}
+					return &Integer{Value: int64(arg.Value)}
+				case *String:
+					value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 10, 64)
+					if err != nil {
+						return newError("cannot convert %q to INTEGER", arg.Value)
+					}
+					return &Integer{Value: value}
+				default:
+					return newError("argument to `int` not supported, got %s",
+						args[0].Type())
+				}
+			},
+		},
+	},
+	{
+		"float",
+		&Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1",
+						len(args))
+				}
+
+				switch arg := args[0].(type) {
+				case *Integer:
+					return &Float{Value: float64(arg.Value)}
+				case *Float:
+					return arg
+				case *String:
+					value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+					if err != nil {
+						return newError("cannot convert %q to FLOAT", arg.Value)
+					}
+					return &Float{Value: value}
+				default:
+					return newError("argument to `float` not supported, got %s",
+						args[0].Type())
+				}
+			},
+		},
+	},
+	{
+		"range",
+		&Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) < 1 || len(args) > 3 {
+					return newError("wrong number of arguments. got=%d, want=1..3",
+						len(args))
+				}
+
+				bounds := make([]int64, len(args))
+				for i, arg := range args {
+					integer, ok := arg.(*Integer)
+					if !ok {
+						return newError("arguments to `range` must be INTEGER, got %s",
+							arg.Type())
+					}
+					bounds[i] = integer.Value
+				}
+
+				r := &Range{Step: 1}
+				switch len(bounds) {
+				case 1:
+					r.Stop = bounds[0]
+				case 2:
+					r.Start, r.Stop = bounds[0], bounds[1]
+				case 3:
+					r.Start, r.Stop, r.Step = bounds[0], bounds[1], bounds[2]
+				}
+
+				if r.Step == 0 {
+					return newError("`range` step must not be zero")
+				}
+
+				return r
+			},
+		},
+	},
 }
 
 func newError(format string, a ...interface{}) *Error {
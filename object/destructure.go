@@ -0,0 +1,52 @@
+package object
+
+import "fmt"
+
+// DestructureArray matches obj against an array pattern of n names,
+// returning the values to bind them to. A pattern with a rest takes
+// any elements beyond the first n, which are returned as an array of
+// their own, following the others.
+func DestructureArray(obj Object, n int, rest bool) ([]Object, error) {
+	arr, ok := obj.(*Array)
+	if !ok {
+		return nil, fmt.Errorf("cannot destructure %s as an array", obj.Type())
+	}
+
+	switch {
+	case rest && len(arr.Elements) < n:
+		return nil, fmt.Errorf("wrong number of elements to destructure: want=%d or more, got=%d", n, len(arr.Elements))
+	case !rest && len(arr.Elements) != n:
+		return nil, fmt.Errorf("wrong number of elements to destructure: want=%d, got=%d", n, len(arr.Elements))
+	}
+
+	values := make([]Object, n, n+1)
+	copy(values, arr.Elements)
+
+	if rest {
+		remaining := make([]Object, len(arr.Elements)-n)
+		copy(remaining, arr.Elements[n:])
+		values = append(values, &Array{Elements: remaining})
+	}
+
+	return values, nil
+}
+
+// DestructureHash matches obj against a hash pattern, returning
+// the values of the given keys. Every key has to be present.
+func DestructureHash(obj Object, keys []string) ([]Object, error) {
+	hash, ok := obj.(*Hash)
+	if !ok {
+		return nil, fmt.Errorf("cannot destructure %s as a hash", obj.Type())
+	}
+
+	values := make([]Object, len(keys))
+	for i, key := range keys {
+		pair, ok := hash.Pairs[(&String{Value: key}).HashKey()]
+		if !ok {
+			return nil, fmt.Errorf("hash has no key %q", key)
+		}
+		values[i] = pair.Value
+	}
+
+	return values, nil
+}
@@ -5,8 +5,10 @@ package object
 // contains a reference to another object.Environment,
 // which mirrors how variable scopes are perceived.
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	consts map[string]bool // The names in store bound to constants.
+	outer  *Environment
+	fn     string // The name of the function call e was created for, if any.
 }
 
 // NewEnclosedEnvironment allows extending an environment by
@@ -20,6 +22,15 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return env
 }
 
+// NewCallEnvironment returns an enclosed environment
+// for a call of the function named fn.
+func NewCallEnvironment(outer *Environment, fn string) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.fn = fn
+	
+	return env
+}
+
 // NewEnvironment returns a new *Environment
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
@@ -36,5 +47,76 @@ func (e *Environment) Get(name string) (Object, bool) {
 
 func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
+	delete(e.consts, name)
+	return val
+}
+
+// SetConst binds name to val like Set does, as a constant.
+func (e *Environment) SetConst(name string, val Object) Object {
+	e.store[name] = val
+	if e.consts == nil {
+		e.consts = make(map[string]bool)
+	}
+	e.consts[name] = true
 	return val
 }
+
+// IsConst reports whether name is bound to a constant
+// in the innermost environment that defines it.
+func (e *Environment) IsConst(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env.consts[name]
+		}
+	}
+	
+	return false
+}
+
+// DefinesConst reports whether name is bound to a constant in e
+// itself, where a let or const statement can't bind it again.
+func (e *Environment) DefinesConst(name string) bool {
+	return e.consts[name]
+}
+
+// IsFunctionName reports whether name refers to a function from within
+// one of its calls, being the name of that call or an enclosing one, and
+// not bound since, like a parameter named the same would be.
+func (e *Environment) IsFunctionName(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return false
+		}
+		if env.fn == name {
+			return true
+		}
+	}
+	
+	return false
+}
+
+// Function returns the name of the function call that e belongs
+// to, as shown in stack traces, or "" outside of any call.
+func (e *Environment) Function() string {
+	for env := e; env != nil; env = env.outer {
+		if env.fn != "" {
+			return env.fn
+		}
+	}
+	
+	return ""
+}
+
+// Assign rebinds name in the innermost environment that defines it,
+// rather than shadowing it in e. It reports false, leaving every
+// environment untouched, if name isn't defined at all.
+func (e *Environment) Assign(name string, val Object) (Object, bool) {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return val, true
+		}
+	}
+	
+	return nil, false
+}
@@ -0,0 +1,43 @@
+package object
+
+// ThrowMessage returns the message of the error raised by throwing
+// value. A string is its own message, and a hash caught by a catch
+// block keeps the message it had. Anything else is inspected.
+func ThrowMessage(value Object) string {
+	switch value := value.(type) {
+	case *String:
+		return value.Value
+	case *Hash:
+		pair, ok := value.Pairs[(&String{Value: "message"}).HashKey()]
+		if msg, isString := pair.Value.(*String); ok && isString {
+			return msg.Value
+		}
+	}
+
+	return value.Inspect()
+}
+
+// ErrorHash returns the hash a catch block receives for err, holding
+// its "message" and its "trace": an array with a string for each
+// frame err unwound through, starting with the innermost one. An
+// error raised by a throw statement also has the thrown "value".
+func ErrorHash(err *Error) *Hash {
+	trace := make([]Object, len(err.Trace))
+	for i, frame := range err.Trace {
+		trace[i] = &String{Value: frame.String()}
+	}
+
+	hash := &Hash{Pairs: make(map[HashKey]HashPair)}
+	set := func(key string, value Object) {
+		k := &String{Value: key}
+		hash.Pairs[k.HashKey()] = HashPair{Key: k, Value: value}
+	}
+
+	set("message", &String{Value: err.Message})
+	set("trace", &Array{Elements: trace})
+	if err.Value != nil {
+		set("value", err.Value)
+	}
+
+	return hash
+}
@@ -0,0 +1,162 @@
+package object
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Iterable is implemented by the objects a for-in loop can walk over.
+type Iterable interface {
+	Iter() Iterator
+}
+
+// Iterator steps through the elements of an Iterable. Next reports
+// false once the elements are exhausted. Otherwise it returns the
+// element along with its key: the index for arrays, strings and ranges,
+// or the hash key for hashes. Iterators are objects themselves, so
+// the VM can keep them on its stack for the duration of a loop.
+type Iterator interface {
+	Object
+	Next() (key, value Object, ok bool)
+}
+
+// Iter returns an iterator over the array's elements.
+func (ao *Array) Iter() Iterator {
+	return &arrayIterator{elements: ao.Elements}
+}
+
+type arrayIterator struct {
+	elements []Object
+	index    int
+}
+
+func (ai *arrayIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (ai *arrayIterator) Inspect() string  { return "<array iterator>" }
+
+func (ai *arrayIterator) Next() (Object, Object, bool) {
+	if ai.index >= len(ai.elements) {
+		return nil, nil, false
+	}
+
+	key := &Integer{Value: int64(ai.index)}
+	value := ai.elements[ai.index]
+	ai.index++
+
+	return key, value, true
+}
+
+// Iter returns an iterator over the string's characters.
+func (s *String) Iter() Iterator {
+	return &stringIterator{runes: s.Runes()}
+}
+
+type stringIterator struct {
+	runes []rune
+	index int
+}
+
+func (si *stringIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (si *stringIterator) Inspect() string  { return "<string iterator>" }
+
+func (si *stringIterator) Next() (Object, Object, bool) {
+	if si.index >= len(si.runes) {
+		return nil, nil, false
+	}
+
+	key := &Integer{Value: int64(si.index)}
+	value := &String{Value: string(si.runes[si.index])}
+	si.index++
+
+	return key, value, true
+}
+
+// Iter returns an iterator over the hash's pairs. They're visited
+// in an unspecified order, which is stable for a given set of keys.
+func (h *Hash) Iter() Iterator {
+	keys := make([]HashKey, 0, len(h.Pairs))
+	for key := range h.Pairs {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Type != keys[j].Type {
+			return keys[i].Type < keys[j].Type
+		}
+		return keys[i].Value < keys[j].Value
+	})
+
+	pairs := make([]HashPair, len(keys))
+	for i, key := range keys {
+		pairs[i] = h.Pairs[key]
+	}
+
+	return &hashIterator{pairs: pairs}
+}
+
+type hashIterator struct {
+	pairs []HashPair
+	index int
+}
+
+func (hi *hashIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (hi *hashIterator) Inspect() string  { return "<hash iterator>" }
+
+func (hi *hashIterator) Next() (Object, Object, bool) {
+	if hi.index >= len(hi.pairs) {
+		return nil, nil, false
+	}
+
+	pair := hi.pairs[hi.index]
+	hi.index++
+
+	return pair.Key, pair.Value, true
+}
+
+// Range is a lazily evaluated sequence of integers, counting
+// from Start up to, but not including, Stop in increments of
+// Step. A negative Step counts down instead.
+type Range struct {
+	Start int64
+	Stop  int64
+	Step  int64
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	if r.Step == 1 {
+		return fmt.Sprintf("range(%d, %d)", r.Start, r.Stop)
+	}
+
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.Stop, r.Step)
+}
+
+// Iter returns an iterator over the range's integers.
+func (r *Range) Iter() Iterator {
+	return &rangeIterator{step: r.Step, stop: r.Stop, next: r.Start, done: r.Step == 0}
+}
+
+type rangeIterator struct {
+	step, stop int64
+	next       int64
+	index      int64
+	done       bool // set once next would overflow, or for a zero step.
+}
+
+func (ri *rangeIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (ri *rangeIterator) Inspect() string  { return "<range iterator>" }
+
+func (ri *rangeIterator) Next() (Object, Object, bool) {
+	if ri.done || ri.step > 0 && ri.next >= ri.stop || ri.step < 0 && ri.next <= ri.stop {
+		return nil, nil, false
+	}
+
+	key := &Integer{Value: ri.index}
+	value := &Integer{Value: ri.next}
+	ri.index++
+
+	next := ri.next + ri.step
+	ri.done = (ri.step > 0) != (next > ri.next)
+	ri.next = next
+
+	return key, value, true
+}
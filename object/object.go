@@ -7,19 +7,25 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/adamwoolhether/monkeyLang/ast"
 	"github.com/adamwoolhether/monkeyLang/code"
+	"github.com/adamwoolhether/monkeyLang/token"
 )
 
 type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
@@ -27,6 +33,10 @@ const (
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
 	CLOSURE_OBJ      = "CLOSURE"
+	RANGE_OBJ        = "RANGE"
+	ITERATOR_OBJ     = "ITERATOR"
+	QUOTE_OBJ        = "QUOTE"
+	MACRO_OBJ        = "MACRO"
 
 	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION_OBJ"
 )
@@ -51,6 +61,25 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+// Float holds the value of
+// ast.FloatLiteral objects.
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// Inspect always renders a fractional part or an exponent,
+// so that floats can't be mistaken for integers, ex: 2.0.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if strings.ContainsAny(s, ".eIN") {
+		return s
+	}
+
+	return s + ".0"
+}
+
 // Boolean holds the value of
 // ast.Boolean objects
 type Boolean struct {
@@ -75,11 +104,30 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Break signals a break statement unwinding to
+// the innermost enclosing loop.
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+// Continue signals a continue statement unwinding
+// to the innermost enclosing loop.
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
 // Error represents an internal error in Monkey. Errors for
 // wrong operators, unsupported operations, and other user
 // or internal errors that can arise during execution.
+// Trace is filled in by the evaluator as the error unwinds
+// through the function calls that led to it. Value holds the
+// value of a throw statement, and is nil for other errors.
 type Error struct {
 	Message string
+	Trace   StackTrace
+	Value   Object
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
@@ -88,10 +136,14 @@ func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 // Function represents a Function internally, holding the function
 // Body, Parameters. It also has an Env field, beacuse monkey
 // functions carry their own environment, which allows for closures.
+// Name is the name the function was bound to, if any.
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   map[string]ast.Expression
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -100,7 +152,14 @@ func (f *Function) Inspect() string {
 
 	params := []string{}
 	for _, p := range f.Parameters {
-		params = append(params, p.String())
+		if def, ok := f.Defaults[p.Value]; ok {
+			params = append(params, p.String()+" = "+def.String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
 	}
 
 	out.WriteString("fn")
@@ -113,15 +172,91 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+// Quote holds an unevaluated AST node, as returned by quote().
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string  { return "QUOTE(" + q.Node.String() + ")" }
+
+// ASTNode returns the literal that evaluates to obj, or the node
+// obj quotes, as unquote() does. Other objects have no AST to
+// convert to.
+func ASTNode(obj Object) (ast.Node, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		t := token.Token{Type: token.INT, Literal: strconv.FormatInt(obj.Value, 10)}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}, true
+	case *Float:
+		t := token.Token{Type: token.FLOAT, Literal: strconv.FormatFloat(obj.Value, 'g', -1, 64)}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}, true
+	case *Boolean:
+		t := token.Token{Type: token.FALSE, Literal: "false"}
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true"}
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}, true
+	case *String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}, true
+	case *Quote:
+		return obj.Node, true
+	default:
+		return nil, false
+	}
+}
+
+// Macro represents a macro bound by a top-level let statement,
+// holding its Env like a Function does. Macros are only called
+// while expanding the program, before it runs.
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
 // String allows representation of strings in Monkey. This is
-// simplified due to go's native support for string.
+// simplified due to go's native support for string. Strings
+// are sequences of Unicode characters, so their length and
+// indices are counted in runes rather than bytes.
 type String struct {
 	Value string
+
+	runes []rune // lazily decoded from Value, see Runes.
 }
 
 func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string  { return s.Value }
 
+// Runes returns the characters of the string. The decoded
+// slice is cached, as strings are immutable.
+func (s *String) Runes() []rune {
+	if s.runes == nil {
+		s.runes = []rune(s.Value)
+	}
+
+	return s.runes
+}
+
 // BuiltinFunction allows implementation of native functions in Monkey.
 // The only restriction is that they need to accept zero or more
 // object.Object as args and return an object.Object.
@@ -135,7 +270,9 @@ func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
 // Array allows arrays to be used in Monkey. It uses
-// go's slice behind the scenes.
+// go's slice behind the scenes. Arrays are shared by
+// reference, so assigning to an element is seen by
+// every binding of the array.
 type Array struct {
 	Elements []Object
 }
@@ -180,6 +317,17 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// HashKey of a float with an integral value is that of the equal
+// integer, so that 1.0 and 1 refer to the same hash entry, as they
+// compare equal.
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return (&Integer{Value: int64(f.Value)}).HashKey()
+	}
+
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 func (s *String) HashKey() HashKey {
 	// TODO: Implement open-addressing or separate-chaining to prevent potential hash collisions.
 	h := fnv.New64a()
@@ -225,8 +373,14 @@ func (h *Hash) Inspect() string {
 // to represent func literals on the stack.
 type CompiledFunction struct {
 	Instructions  code.Instructions
-	NumLocals     int // How many local bindings the func will create.
-	NumParameters int
+	NumLocals     int            // How many local bindings the func will create.
+	NumParameters int            // How many named parameters the func has, not counting a rest parameter.
+	NumDefaults   int            // How many of the last parameters have a default value.
+	Variadic      bool           // Whether a rest parameter follows the named ones.
+	Name          string         // The name the func was bound to, if any.
+	Pos           token.Position // Where the func literal was defined.
+	SourceMap     code.SourceMap // Source positions of the Instructions.
+	Handlers      []code.Handler // The exception handlers of the func's try statements.
 }
 
 func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
@@ -234,6 +388,26 @@ func (cf *CompiledFunction) Inspect() string {
 	return fmt.Sprintf("CompiledFunction[%p]", cf)
 }
 
+// CheckArity returns an error unless a call can pass numArgs arguments
+// to a func with numParams named parameters, the last numDefaults of
+// which have a default value, followed by a rest parameter if variadic.
+func CheckArity(numArgs, numParams, numDefaults int, variadic bool) error {
+	required := numParams - numDefaults
+	if numArgs >= required && (variadic || numArgs <= numParams) {
+		return nil
+	}
+
+	want := strconv.Itoa(numParams)
+	switch {
+	case variadic:
+		want = fmt.Sprintf("%d or more", required)
+	case numDefaults > 0:
+		want = fmt.Sprintf("%d..%d", required, numParams)
+	}
+
+	return fmt.Errorf("wrong number of arguments: want=%s, got=%d", want, numArgs)
+}
+
 type Closure struct {
 	Fn   *CompiledFunction
 	Free []Object
@@ -1,6 +1,11 @@
 package object
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	
+	"github.com/adamwoolhether/monkeyLang/token"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -20,3 +25,197 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with the same content have different hash keys")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	if (&Float{Value: 1.5}).HashKey() != (&Float{Value: 1.5}).HashKey() {
+		t.Errorf("floats with the same value have different hash keys")
+	}
+	
+	if (&Float{Value: 1.5}).HashKey() == (&Float{Value: 2.5}).HashKey() {
+		t.Errorf("floats with different values have the same hash key")
+	}
+	
+	if (&Float{Value: 2}).HashKey() != (&Integer{Value: 2}).HashKey() {
+		t.Errorf("integral float and equal integer have different hash keys")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-3, "-3.0"},
+		{1e21, "1e+21"},
+		{0.001, "0.001"},
+	}
+	
+	for _, tt := range tests {
+		if got := (&Float{Value: tt.value}).Inspect(); got != tt.expected {
+			t.Errorf("wrong Inspect for %g. want=%q, got=%q", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestStackTraceString(t *testing.T) {
+	trace := StackTrace{
+		{Function: "add", Pos: token.Position{Filename: "main.mk", Line: 2, Column: 3}},
+		{Function: MainFunctionName},
+	}
+	
+	expected := "    at add (main.mk:2:3)\n    at <main>"
+	if trace.String() != expected {
+		t.Errorf("wrong trace. want=%q, got=%q", expected, trace.String())
+	}
+}
+
+func TestIterators(t *testing.T) {
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, key := range []Object{&Integer{Value: 2}, &Integer{Value: 1}, &Boolean{Value: true}} {
+		hash.Pairs[key.(Hashable).HashKey()] = HashPair{Key: key, Value: &String{Value: key.Inspect()}}
+	}
+	
+	tests := []struct {
+		iterable Iterable
+		expected string
+	}{
+		{&Array{Elements: []Object{&Integer{Value: 5}, &String{Value: "a"}}}, "0:5 1:a"},
+		{&Array{}, ""},
+		{&String{Value: "héy"}, "0:h 1:é 2:y"},
+		{hash, "true:true 1:1 2:2"},
+		{&Range{Start: 0, Stop: 3, Step: 1}, "0:0 1:1 2:2"},
+		{&Range{Start: 10, Stop: 0, Step: -4}, "0:10 1:6 2:2"},
+		{&Range{Start: 3, Stop: 3, Step: 1}, ""},
+		{&Range{Start: 0, Stop: 3, Step: 0}, ""},
+		{&Range{Start: 9223372036854775806, Stop: 9223372036854775807, Step: 5}, "0:9223372036854775806"},
+	}
+	
+	for _, tt := range tests {
+		var elements []string
+		
+		iter := tt.iterable.Iter()
+		for {
+			key, value, ok := iter.Next()
+			if !ok {
+				break
+			}
+			elements = append(elements, key.Inspect()+":"+value.Inspect())
+		}
+		
+		if got := strings.Join(elements, " "); got != tt.expected {
+			t.Errorf("%s: wrong elements. want=%q, got=%q", tt.iterable.(Object).Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+	
+	if _, ok := inner.Assign("x", &Integer{Value: 2}); !ok {
+		t.Fatalf("assigning to x failed")
+	}
+	
+	if _, ok := inner.store["x"]; ok {
+		t.Errorf("assignment shadowed x in the inner environment")
+	}
+	if x, _ := outer.Get("x"); x.(*Integer).Value != 2 {
+		t.Errorf("outer x wasn't assigned. got=%s", x.Inspect())
+	}
+	
+	if _, ok := inner.Assign("y", &Integer{Value: 3}); ok {
+		t.Errorf("assigning to undefined y succeeded")
+	}
+	if _, ok := inner.Get("y"); ok {
+		t.Errorf("assigning to undefined y defined it")
+	}
+}
+
+func TestEnvironmentConst(t *testing.T) {
+	outer := NewEnvironment()
+	outer.SetConst("x", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+	
+	if !inner.IsConst("x") {
+		t.Errorf("x isn't constant in the inner environment")
+	}
+	
+	inner.Set("x", &Integer{Value: 2})
+	if inner.IsConst("x") {
+		t.Errorf("x shadowed by a variable is constant")
+	}
+	if !outer.IsConst("x") {
+		t.Errorf("shadowing x made it a variable in the outer environment")
+	}
+	
+	outer.Set("x", &Integer{Value: 3})
+	if outer.IsConst("x") {
+		t.Errorf("redefined x is still constant")
+	}
+}
+
+func TestEnvironmentFunction(t *testing.T) {
+	global := NewEnvironment()
+	call := NewCallEnvironment(global, "f")
+	block := NewEnclosedEnvironment(call)
+	
+	if fn := global.Function(); fn != "" {
+		t.Errorf("global environment belongs to a call. got=%q", fn)
+	}
+	if fn := block.Function(); fn != "f" {
+		t.Errorf("block belongs to the wrong call. want=%q, got=%q", "f", fn)
+	}
+}
+
+func TestEnvironmentIsFunctionName(t *testing.T) {
+	global := NewEnvironment()
+	global.Set("f", &Integer{Value: 1})
+	call := NewCallEnvironment(global, "f")
+	closure := NewCallEnvironment(NewEnclosedEnvironment(call), AnonymousFunctionName)
+	
+	if global.IsFunctionName("f") {
+		t.Errorf("f is a function name outside of its calls")
+	}
+	if !closure.IsFunctionName("f") {
+		t.Errorf("f isn't a function name within a closure in its call")
+	}
+	
+	call.Set("f", &Integer{Value: 2})
+	if closure.IsFunctionName("f") {
+		t.Errorf("f shadowed in its call is still a function name")
+	}
+}
+
+func TestErrorHash(t *testing.T) {
+	err := &Error{
+		Message: "boom",
+		Trace:   StackTrace{{Function: "f", Pos: token.Position{Line: 1, Column: 2}}},
+		Value:   &Integer{Value: 1},
+	}
+	
+	hash := ErrorHash(err)
+	
+	get := func(key string) Object {
+		return hash.Pairs[(&String{Value: key}).HashKey()].Value
+	}
+	
+	if msg, ok := get("message").(*String); !ok || msg.Value != "boom" {
+		t.Errorf("wrong message. got=%v", get("message"))
+	}
+	if trace, ok := get("trace").(*Array); !ok || trace.Inspect() != "[at f (1:2)]" {
+		t.Errorf("wrong trace. got=%v", get("trace"))
+	}
+	if get("value") != err.Value {
+		t.Errorf("wrong value. got=%v", get("value"))
+	}
+	
+	if ThrowMessage(hash) != "boom" {
+		t.Errorf("rethrowing the hash changed its message. got=%q", ThrowMessage(hash))
+	}
+	if ThrowMessage(&Integer{Value: 1}) != "1" {
+		t.Errorf("wrong message for an integer. got=%q", ThrowMessage(&Integer{Value: 1}))
+	}
+}
@@ -0,0 +1,59 @@
+package object
+
+import (
+	"strings"
+
+	"github.com/adamwoolhether/monkeyLang/token"
+)
+
+// Names used in stack traces for frames that have no function name.
+const (
+	MainFunctionName      = "<main>"
+	AnonymousFunctionName = "<anonymous>"
+)
+
+// StackFrame describes a function call that was active when
+// a runtime error occurred, and where execution was within it.
+type StackFrame struct {
+	Function string
+	Pos      token.Position
+}
+
+// String renders the frame as "at name (file:line:col)",
+// leaving out the position if it's unknown.
+func (sf StackFrame) String() string {
+	if !sf.Pos.IsValid() {
+		return "at " + sf.Function
+	}
+
+	return "at " + sf.Function + " (" + sf.Pos.String() + ")"
+}
+
+// StackTrace lists the frames of a runtime error,
+// starting with the innermost call.
+type StackTrace []StackFrame
+
+// String renders each frame on its own indented line.
+func (st StackTrace) String() string {
+	var out strings.Builder
+
+	for i, frame := range st {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString("    ")
+		out.WriteString(frame.String())
+	}
+
+	return out.String()
+}
+
+// FunctionName returns the name to show in a stack trace
+// for a function bound to name, which may be empty.
+func FunctionName(name string) string {
+	if name == "" {
+		return AnonymousFunctionName
+	}
+
+	return name
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/adamwoolhether/monkeyLang/token"
+)
+
+// ParseError describes a single syntax error found by the parser.
+// Expected holds the token types that would have been accepted,
+// when the parser knows them.
+type ParseError struct {
+	Pos      token.Position
+	Expected []token.TokenType
+	Got      token.Token
+	Msg      string
+	Hint     string // optional suggestion on how to fix the error
+}
+
+// Error returns the message prefixed by its source position.
+func (e *ParseError) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Msg
+	}
+
+	return e.Msg
+}
+
+// hints holds suggestions for the most common cases of a missing token.
+var hints = map[token.TokenType]string{
+	token.RPAREN:   "check for a missing ')'",
+	token.RBRACE:   "check for a missing '}'",
+	token.RBRACKET: "check for a missing ']'",
+	token.LBRACE:   "blocks must be wrapped in '{' and '}'",
+	token.IDENT:    "expected a name here, e.g. let x = 5;",
+	token.ASSIGN:   "bindings need an initial value, e.g. let x = 5;",
+	token.COLON:    "hash entries are written as key: value",
+}
+
+// describeToken renders a token for use in error messages.
+func describeToken(tok token.Token) string {
+	switch tok.Type {
+	case token.EOF:
+		return "end of input"
+	case token.ILLEGAL:
+		return fmt.Sprintf("illegal character %q", tok.Literal)
+	case token.IDENT:
+		return "identifier " + tok.Literal
+	case token.INT, token.FLOAT:
+		return "number " + tok.Literal
+	case token.STRING:
+		return "string literal"
+	default:
+		return "'" + tok.Literal + "'"
+	}
+}
+
+// RenderErrors writes each error to w along with the offending
+// line of source and a caret pointing at the error's column.
+func RenderErrors(w io.Writer, source string, errs []*ParseError) {
+	lines := strings.Split(source, "\n")
+
+	for _, e := range errs {
+		fmt.Fprintf(w, "%s\n", e.Error())
+
+		if e.Pos.IsValid() && e.Pos.Line <= len(lines) {
+			line := strings.TrimRight(lines[e.Pos.Line-1], "\r")
+			fmt.Fprintf(w, "    %s\n", line)
+			fmt.Fprintf(w, "    %s^\n", caretPadding(line, e.Pos.Column))
+		}
+
+		if e.Hint != "" {
+			fmt.Fprintf(w, "    hint: %s\n", e.Hint)
+		}
+	}
+}
+
+// caretPadding returns the whitespace needed to place a caret under the
+// given 1-based column of line, keeping tabs so the caret lines up.
+func caretPadding(line string, column int) string {
+	var pad strings.Builder
+
+	col := 1
+	for _, r := range line {
+		if col >= column {
+			break
+		}
+		if r == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteRune(' ')
+		}
+		col++
+	}
+
+	for ; col < column; col++ {
+		pad.WriteRune(' ')
+	}
+
+	return pad.String()
+}
@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/adamwoolhether/monkeyLang/lexer"
+	"github.com/adamwoolhether/monkeyLang/token"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedMessages []string
+	}{
+		{
+			"let x 5;",
+			[]string{"1:7: expected next token to be =, got number 5 instead"},
+		},
+		{
+			"let = 10; let y = 2;",
+			[]string{"1:5: expected next token to be IDENT, got '=' instead"},
+		},
+		{
+			// A single typo yields a single error, and parsing resumes
+			// at the next statement.
+			"let a = (1 + 2; let b = 3; let c = 4 +; c;",
+			[]string{
+				"1:15: expected next token to be ), got ';' instead",
+				"1:39: unexpected ';'",
+			},
+		},
+		{
+			"if (x { 1 } let y = 2 } let z 3;",
+			[]string{
+				"1:7: expected next token to be ), got '{' instead",
+				"1:23: unexpected '}'",
+				"1:31: expected next token to be =, got number 3 instead",
+			},
+		},
+		{
+			// Errors inside function bodies resynchronize on the
+			// statement boundaries within the block.
+			"let f = fn() { let = 1; return 2; }; f(;",
+			[]string{
+				"1:20: expected next token to be IDENT, got '=' instead",
+				"1:40: unexpected ';'",
+			},
+		},
+		{
+			"let f = fn() { 1",
+			[]string{"1:17: unexpected end of input, expected '}'"},
+		},
+		{
+			`let s = "abc`,
+			[]string{"1:9: unterminated string literal"},
+		},
+		{
+			"let big = 9223372036854775808;",
+			[]string{"1:11: integer literal 9223372036854775808 out of range"},
+		},
+		{
+			"0x; 0b102; 1__000; 100_;",
+			[]string{
+				"1:1: hexadecimal literal 0x has no digits",
+				"1:5: invalid digit '2' in binary literal 0b102",
+				"1:12: '_' must separate successive digits in 1__000",
+				"1:20: '_' must separate successive digits in 100_",
+			},
+		},
+		{
+			"let a = 1; /* unclosed",
+			[]string{"1:12: unterminated block comment"},
+		},
+		{
+			"a + b = 1; f() += 2;",
+			[]string{
+				"1:1: cannot assign to (a + b)",
+				"1:12: cannot assign to f()",
+			},
+		},
+		{
+			"let [a, ...b, c] = xs; let {a: b} = h;",
+			[]string{
+				"1:13: the rest of an array pattern must come last",
+				"1:30: expected next token to be ,, got ':' instead",
+			},
+		},
+		{
+			"fn(a = 1, b) {}; fn(...r, a) {}; fn(a, 1) {}",
+			[]string{
+				"1:11: parameter b needs a default value, like the ones before it",
+				"1:25: the rest parameter must come last",
+				"1:40: expected next token to be IDENT, got number 1 instead",
+			},
+		},
+		{
+			"let f = fn(a = 1, a = 2) { a }; fn(a, ...a) {}; macro(x, x) { x };",
+			[]string{
+				"1:19: duplicate parameter a",
+				"1:42: duplicate parameter a",
+				"1:58: duplicate parameter x",
+			},
+		},
+		{
+			"try { 1 } let x = 2; try { 1 } catch e { e }",
+			[]string{
+				"1:11: expected catch or finally after try block",
+				"1:38: expected next token to be (, got identifier e instead",
+			},
+		},
+		{
+			// A loop may only be left by an if whose value goes unused.
+			"while (x) { let z = [1, if (x) { continue; } else { 2 }]; if (x) { if (y) { break; } } let f = fn() { while (x) { if (x) { break; } } }; }",
+			[]string{"1:34: continue can't be part of an expression"},
+		},
+		{
+			"while (x) { 10 + if (x) { break; } else { 1 }; let y = if (x) { while (x) { break; } }; }",
+			[]string{"1:27: break can't be part of an expression"},
+		},
+		{
+			"let s = []; for (x in [1,2,3]) { s = push(s, 10 + if (x == 2) { continue; } else { x }); }",
+			[]string{"1:65: continue can't be part of an expression"},
+		},
+		{
+			// A function's body can't leave the loop it's called from.
+			"break; if (false) { continue; } while (x) { let f = fn(a = if (x) { break; }) { continue; }; }",
+			[]string{
+				"1:1: break outside of loop",
+				"1:21: continue outside of loop",
+				"1:69: break outside of loop",
+				"1:81: continue outside of loop",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errs := p.Errors()
+		if len(errs) != len(tt.expectedMessages) {
+			t.Errorf("input %q: wrong number of errors. want=%d, got=%d (%v)",
+				tt.input, len(tt.expectedMessages), len(errs), errs)
+			continue
+		}
+
+		for i, msg := range tt.expectedMessages {
+			if errs[i].Error() != msg {
+				t.Errorf("input %q: wrong error. want=%q, got=%q", tt.input, msg, errs[i].Error())
+			}
+		}
+	}
+}
+
+func TestParseErrorDetails(t *testing.T) {
+	p := New(lexer.NewWithFilename("main.mk", "let x = add(1, 2;"))
+	p.ParseProgram()
+
+	errs := p.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("wrong number of errors. want=1, got=%d", len(errs))
+	}
+
+	err := errs[0]
+	if err.Pos.String() != "main.mk:1:17" {
+		t.Errorf("wrong position. want=%q, got=%q", "main.mk:1:17", err.Pos)
+	}
+	if len(err.Expected) != 1 || err.Expected[0] != token.RPAREN {
+		t.Errorf("wrong expected tokens. got=%v", err.Expected)
+	}
+	if err.Got.Type != token.SEMICOLON {
+		t.Errorf("wrong got token. want=%q, got=%q", token.SEMICOLON, err.Got.Type)
+	}
+	if err.Hint == "" {
+		t.Errorf("expected a hint for a missing ')'")
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	input := "let a = 1;\n\tlet b = [1, 2;\n"
+
+	p := New(lexer.NewWithFilename("main.mk", input))
+	p.ParseProgram()
+
+	var out bytes.Buffer
+	RenderErrors(&out, input, p.Errors())
+
+	expected := "main.mk:2:15: expected next token to be ], got ';' instead\n" +
+		"    \tlet b = [1, 2;\n" +
+		"    \t             ^\n" +
+		"    hint: check for a missing ']'\n"
+
+	if out.String() != expected {
+		t.Errorf("wrong rendering.\nwant=%q\ngot =%q", expected, out.String())
+	}
+}
@@ -5,6 +5,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/adamwoolhether/monkeyLang/ast"
 	"github.com/adamwoolhether/monkeyLang/lexer"
@@ -15,11 +16,19 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // = or +=, right-associative
+	OR          // ||
+	AND         // &&
 	EQUALS      // ==
 	LESSGREATER // > or <
+	BITWISE_OR  // |
+	BITWISE_XOR // ^
+	BITWISE_AND // &
+	SHIFT       // << or >>
 	SUM         // +
-	PRODUCT     // *
-	PREFIX      // -X or !X
+	PRODUCT     // * or / or %
+	PREFIX      // -X or !X or ~X
+	POWER       // **, binds tighter than a prefix operator on its left: -2 ** 2 == -4
 	CALL        // myFunction(X)
 	INDEX       // array[index]
 )
@@ -31,10 +40,29 @@ var precedences = map[token.TokenType]int{
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LT_EQ:    LESSGREATER,
+	token.GT_EQ:    LESSGREATER,
+	token.AND:      AND,
+	token.OR:       OR,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.PERCENT:  PRODUCT,
+	token.POWER:    POWER,
+
+	token.PIPE:      BITWISE_OR,
+	token.CARET:     BITWISE_XOR,
+	token.AMPERSAND: BITWISE_AND,
+	token.LSHIFT:    SHIFT,
+	token.RSHIFT:    SHIFT,
+
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
 }
@@ -55,11 +83,19 @@ type (
 // on whether the token is found in a prefix or infix position.
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []*ParseError
+	// panicking is set once an error has been reported for the current
+	// statement, suppressing the cascade of errors that would otherwise
+	// follow until the parser synchronizes on a statement boundary.
+	panicking bool
 
 	curToken  token.Token
 	peekToken token.Token
 
+	// comments collects the comments the lexer emits, if it was
+	// asked to, as they'd otherwise get in the way of parsing.
+	comments []*ast.Comment
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
@@ -80,19 +116,22 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*ParseError{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
@@ -102,10 +141,26 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
+	p.registerInfix(token.LSHIFT, p.parseInfixExpression)
+	p.registerInfix(token.RSHIFT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
@@ -123,22 +178,78 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
-// Errors returns a slice of error strings that the parser may encounter.
-func (p *Parser) Errors() []string {
+// Errors returns the syntax errors the parser encountered, in source order.
+func (p *Parser) Errors() []*ParseError {
 	return p.errors
 }
 
+// addError records err unless an error was already
+// reported for the statement currently being parsed.
+func (p *Parser) addError(err *ParseError) {
+	if p.panicking {
+		return
+	}
+
+	p.panicking = true
+	p.errors = append(p.errors, err)
+}
+
 // peekErrors appends an error to p.errors when the type of peekToken
 // doesn't match the expectation.
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(&ParseError{
+		Pos:      p.peekToken.Pos,
+		Expected: []token.TokenType{t},
+		Got:      p.peekToken,
+		Msg:      fmt.Sprintf("expected next token to be %s, got %s instead", t, describeToken(p.peekToken)),
+		Hint:     hints[t],
+	})
+}
+
+// synchronize discards tokens after a syntax error until the end of the
+// offending statement: a semicolon, or the token before a '}' closing
+// the enclosing block or a keyword starting a new statement. Brackets
+// opened along the way are skipped over as a whole.
+func (p *Parser) synchronize() {
+	depth := 0
+
+	for !p.peekTokenIs(token.EOF) {
+		if depth == 0 {
+			if p.curTokenIs(token.SEMICOLON) {
+				break
+			}
+			if p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.LET) || p.peekTokenIs(token.CONST) ||
+				p.peekTokenIs(token.RETURN) || p.peekTokenIs(token.WHILE) || p.peekTokenIs(token.FOR) ||
+				p.peekTokenIs(token.TRY) || p.peekTokenIs(token.THROW) {
+				break
+			}
+		}
+
+		p.nextToken()
+
+		switch p.curToken.Type {
+		case token.LBRACE, token.LPAREN, token.LBRACKET:
+			depth++
+		case token.RBRACE, token.RPAREN, token.RBRACKET:
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+
+	p.panicking = false
 }
 
 // nextToken is a helper func that advances both curToken and peekToken.
+// Comments are set aside rather than becoming the peekToken.
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	for p.peekToken.Type == token.COMMENT {
+		p.comments = append(p.comments, &ast.Comment{Token: p.peekToken})
+		p.peekToken = p.l.NextToken()
+	}
 }
 
 // ParseProgram construct the AST's root node, iterates over
@@ -150,23 +261,54 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		if stmt != nil {
+		if stmt := p.parseStatementOrSync(); stmt != nil {
+			ast.Walk(loopControlChecker{p: p}, stmt)
 			program.Statements = append(program.Statements, stmt)
 		}
 		p.nextToken()
 	}
 
+	program.Comments = p.comments
+
 	return program
 }
 
+// parseStatementOrSync parses a statement, returning nil and skipping
+// to the next statement boundary if it contained a syntax error.
+func (p *Parser) parseStatementOrSync() ast.Statement {
+	numErrors := len(p.errors)
+
+	stmt := p.parseStatement()
+	if len(p.errors) > numErrors {
+		p.synchronize()
+		return nil
+	}
+
+	return stmt
+}
+
 // parseStatement decides how to handle the current token based on its type.
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
+		if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+			return p.parseDestructuringLetStatement()
+		}
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForInStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -202,6 +344,108 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+// parseDestructuringLetStatement parses a let or const
+// statement binding a pattern, ex: let [a, b] = xs;
+func (p *Parser) parseDestructuringLetStatement() *ast.DestructuringLetStatement {
+	stmt := &ast.DestructuringLetStatement{Token: p.curToken}
+
+	p.nextToken()
+	if p.curTokenIs(token.LBRACKET) {
+		stmt.Pattern = p.parseArrayPattern()
+	} else {
+		stmt.Pattern = p.parseHashPattern()
+	}
+	if stmt.Pattern == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseArrayPattern parses the names of an array pattern, the last
+// of which may be preceded by '...' to take the remaining elements.
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+			if !p.peekTokenIs(token.RBRACKET) {
+				p.addError(&ParseError{
+					Pos:      p.peekToken.Pos,
+					Expected: []token.TokenType{token.RBRACKET},
+					Got:      p.peekToken,
+					Msg:      "the rest of an array pattern must come last",
+				})
+				return nil
+			}
+			break
+		}
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	pattern.Rbracket = p.curToken
+
+	return pattern
+}
+
+// parseHashPattern parses the names of a hash pattern.
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			p.skipPast(token.RBRACE)
+			return nil
+		}
+		pattern.Keys = append(pattern.Keys, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			p.skipPast(token.RBRACE)
+			return nil
+		}
+	}
+
+	p.nextToken()
+	pattern.Rbrace = p.curToken
+
+	return pattern
+}
+
+// skipPast advances to the end token of a malformed construct, so
+// that synchronize doesn't take a closing '}' for the end of a block.
+func (p *Parser) skipPast(end token.TokenType) {
+	for !p.curTokenIs(end) && !p.curTokenIs(token.EOF) {
+		p.nextToken()
+	}
+}
+
 // expectPeek is an assertion function that enforces the correctness
 // of token ordering by checking the next token's type.
 func (p *Parser) expectPeek(t token.TokenType) bool {
@@ -239,6 +483,264 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+// parseWhileStatement parses a loop of the form
+// 'while (<condition>) { <body> }' and its optional semicolons.
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseForInStatement parses a loop of the form
+// 'for (<value> in <iterable>) { <body> }', where the
+// value may be preceded by a key: 'for (<key>, <value> in ...'.
+// The loop may be followed by optional semicolons.
+func (p *Parser) parseForInStatement() *ast.ForInStatement {
+	stmt := &ast.ForInStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Key = stmt.Value
+		stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseBreakStatement parses a 'break' and its optional semicolon.
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseContinueStatement parses a 'continue' and its optional semicolon.
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// loopControlChecker reports the break and continue statements that
+// aren't within the body of a loop, and those whose loop would be left
+// with a value still pending, as they are part of an expression, ex:
+// 1 + if (x) { break; } else { 2 }. Only an if whose value goes unused,
+// being a statement of its own, may leave a loop.
+type loopControlChecker struct {
+	p      *Parser
+	inLoop bool // the node being visited is within a loop's body
+	inExpr bool // the value of the node being visited is used
+}
+
+func (c loopControlChecker) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.BreakStatement:
+		c.check(n.Token)
+	case *ast.ContinueStatement:
+		c.check(n.Token)
+
+	case *ast.ExpressionStatement:
+		if ie, ok := n.Expression.(*ast.IfExpression); ok {
+			c.walk(ie.Condition, c.inLoop, true)
+			c.walk(ie.Consequence, c.inLoop, c.inExpr)
+			c.walk(ie.Alternative, c.inLoop, c.inExpr)
+			return nil
+		}
+		return loopControlChecker{p: c.p, inLoop: c.inLoop, inExpr: true}
+
+	// A loop's body starts afresh, as does a function's,
+	// which can't leave the loop it's called from.
+	case *ast.WhileStatement:
+		c.walk(n.Condition, c.inLoop, true)
+		c.walk(n.Body, true, false)
+		return nil
+	case *ast.ForInStatement:
+		c.walk(n.Iterable, c.inLoop, true)
+		c.walk(n.Body, true, false)
+		return nil
+	case *ast.FunctionLiteral:
+		for _, def := range n.Defaults {
+			c.walk(def, false, true)
+		}
+		c.walk(n.Body, false, false)
+		return nil
+	case *ast.MacroLiteral:
+		c.walk(n.Body, false, false)
+		return nil
+
+	case ast.Expression:
+		return loopControlChecker{p: c.p, inLoop: c.inLoop, inExpr: true}
+	}
+
+	return c
+}
+
+// walk walks node, which may be nil, noting whether it's within
+// a loop's body and whether its value is used.
+func (c loopControlChecker) walk(node ast.Node, inLoop, inExpr bool) {
+	if b, ok := node.(*ast.BlockStatement); node == nil || ok && b == nil {
+		return
+	}
+
+	ast.Walk(loopControlChecker{p: c.p, inLoop: inLoop, inExpr: inExpr}, node)
+}
+
+// check reports the break or continue tok if it's outside
+// of a loop or part of an expression.
+func (c loopControlChecker) check(tok token.Token) {
+	switch {
+	case !c.inLoop:
+		c.p.errors = append(c.p.errors, &ParseError{
+			Pos:  tok.Pos,
+			Got:  tok,
+			Msg:  fmt.Sprintf("%s outside of loop", tok.Literal),
+			Hint: fmt.Sprintf("%s may only appear in the body of a while or for loop", tok.Literal),
+		})
+	case c.inExpr:
+		c.p.errors = append(c.p.errors, &ParseError{
+			Pos:  tok.Pos,
+			Got:  tok,
+			Msg:  fmt.Sprintf("%s can't be part of an expression", tok.Literal),
+			Hint: fmt.Sprintf("use an if statement of its own, e.g. if (x) { %s; }", tok.Literal),
+		})
+	}
+}
+
+// parseTryStatement parses a statement of the form
+// 'try { <block> } catch (<param>) { <catch> } finally { <finally> }',
+// where either the catch or the finally clause may be left out.
+// The statement may be followed by optional semicolons.
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.addError(&ParseError{
+			Pos:      p.peekToken.Pos,
+			Expected: []token.TokenType{token.CATCH, token.FINALLY},
+			Got:      p.peekToken,
+			Msg:      "expected catch or finally after try block",
+		})
+		return nil
+	}
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseThrowStatement parses 'throw <value>' and its optional semicolon.
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 // parseExpressionStatement constructs an *ast.Statement node with
 // the current token, skipping over until it encounters a
 // semicolon. The semicolon is optional, allowing expression
@@ -260,8 +762,24 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 // p.errors when there is no suitable parsing function
 // available for the given token.
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse functions for %s found", t)
-	p.errors = append(p.errors, msg)
+	err := &ParseError{
+		Pos: p.curToken.Pos,
+		Got: p.curToken,
+		Msg: fmt.Sprintf("unexpected %s", describeToken(p.curToken)),
+	}
+
+	switch t {
+	case token.ILLEGAL:
+		if msg, ok := p.l.ErrorAt(p.curToken.Pos); ok {
+			err.Msg = msg
+		}
+	case token.RBRACE, token.RPAREN, token.RBRACKET:
+		err.Hint = fmt.Sprintf("check for an unbalanced '%s'", p.curToken.Literal)
+	case token.EOF:
+		err.Hint = "the input ended in the middle of an expression"
+	}
+
+	p.addError(err)
 }
 
 // parseExpression checks if the parsing func associated with
@@ -290,24 +808,26 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 
-// peekPrecedence returns the precedence of the token for
-// p.peekToken, returning LOWEST if one not found.
-func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
+// Precedence returns the precedence of t as an infix
+// operator, returning LOWEST if it isn't one.
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
 		return p
 	}
 
 	return LOWEST
 }
 
+// peekPrecedence returns the precedence of the token for
+// p.peekToken, returning LOWEST if one not found.
+func (p *Parser) peekPrecedence() int {
+	return Precedence(p.peekToken.Type)
+}
+
 // curPrecedence returns the precedence of the token for
 // p.curToken, returning LOWEST if none is found.
 func (p *Parser) curPrecedence() int {
-	if p, ok := precedences[p.curToken.Type]; ok {
-		return p
-	}
-
-	return LOWEST
+	return Precedence(p.curToken.Type)
 }
 
 // parseIntegerLiteral returns a *ast.IntegerLiteral
@@ -316,10 +836,13 @@ func (p *Parser) curPrecedence() int {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	value, err := parseInteger(p.curToken.Literal)
 	if err != nil {
-		msg := fmt.Sprintf("couldn't parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(&ParseError{
+			Pos: p.curToken.Pos,
+			Got: p.curToken,
+			Msg: err.Error(),
+		})
 		return nil
 	}
 
@@ -328,8 +851,85 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
-// parsePrefixExpression builds and returns a PrefixExpresion
-// AST node for tokens of type token.BANG or token.MINUS. It
+// parseInteger converts an integer literal to its value. Literals may have
+// a 0x, 0o or 0b prefix, and may use '_' to separate digits, ex: 0xFF_FF.
+// A '_' may also directly follow a prefix. Without a prefix a literal is
+// decimal, even if it has leading zeros.
+func parseInteger(literal string) (int64, error) {
+	base, digits, kind := 10, literal, "decimal"
+	if len(literal) > 1 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			base, digits, kind = 16, literal[2:], "hexadecimal"
+		case 'o', 'O':
+			base, digits, kind = 8, literal[2:], "octal"
+		case 'b', 'B':
+			base, digits, kind = 2, literal[2:], "binary"
+		}
+	}
+
+	if digits == "" {
+		return 0, fmt.Errorf("%s literal %s has no digits", kind, literal)
+	}
+
+	for i, ch := range digits {
+		if ch == '_' {
+			if i == 0 && base == 10 || i == len(digits)-1 || digits[i+1] == '_' {
+				return 0, fmt.Errorf("'_' must separate successive digits in %s", literal)
+			}
+			continue
+		}
+
+		if digitValue(ch) >= base {
+			return 0, fmt.Errorf("invalid digit %q in %s literal %s", ch, kind, literal)
+		}
+	}
+
+	value, err := strconv.ParseInt(strings.ReplaceAll(digits, "_", ""), base, 64)
+	if err != nil {
+		return 0, fmt.Errorf("integer literal %s out of range", literal)
+	}
+
+	return value, nil
+}
+
+// digitValue returns the value of a digit in bases up to 16,
+// or 16 if ch isn't a digit in any of them.
+func digitValue(ch rune) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'f':
+		return int(ch-'a') + 10
+	case 'A' <= ch && ch <= 'F':
+		return int(ch-'A') + 10
+	default:
+		return 16
+	}
+}
+
+// parseFloatLiteral returns a *ast.FloatLiteral
+// with the value of the current token.
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.addError(&ParseError{
+			Pos: p.curToken.Pos,
+			Got: p.curToken,
+			Msg: fmt.Sprintf("couldn't parse %q as float", p.curToken.Literal),
+		})
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+// parsePrefixExpression builds and returns a PrefixExpresion AST
+// node for tokens of type token.BANG, token.MINUS or token.TILDE. It
 // also advances to the next token and calls p.parseExpression
 // to correctly parse an express like '-5', as more than one
 // token must be consumed, setting the expression to expression.Right.
@@ -350,6 +950,8 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 // an *ast.InfixExpression node assigning the Left field and the
 // current token's precedence (the infix expressions operator) to
 // the local var before advancing and assigning the Right field.
+// The right operand of '**' is parsed with a lowered precedence,
+// making it right-associative: 2 ** 3 ** 2 == 2 ** (3 ** 2).
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.curToken,
@@ -358,24 +960,57 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	if p.curTokenIs(token.POWER) {
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
 	return expression
 }
 
+// parseAssignExpression parses an assignment to the binding, or
+// the array or hash element, on its left. Assignments are right-
+// associative, so 'a = b = 1' assigns 1 to b, and then the result
+// of that to a.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
+
+	switch ast.Unparen(target).(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		p.addError(&ParseError{
+			Pos: target.Pos(),
+			Got: p.curToken,
+			Msg: fmt.Sprintf("cannot assign to %s", target.String()),
+		})
+		return nil
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	exp := &ast.ParenExpression{Token: p.curToken}
 	p.nextToken()
 
-	exp := p.parseExpression(LOWEST)
+	exp.Expression = p.parseExpression(LOWEST)
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
+	exp.Rparen = p.curToken
 
 	return exp
 }
@@ -427,13 +1062,24 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		if stmt != nil {
+		if stmt := p.parseStatementOrSync(); stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
 
+	if p.curTokenIs(token.EOF) {
+		p.addError(&ParseError{
+			Pos:      p.curToken.Pos,
+			Expected: []token.TokenType{token.RBRACE},
+			Got:      p.curToken,
+			Msg:      "unexpected end of input, expected '}'",
+			Hint:     fmt.Sprintf("the block opened at %s is never closed", block.Token.Pos),
+		})
+	}
+
+	block.Rbrace = p.curToken
+
 	return block
 }
 
@@ -445,7 +1091,9 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -456,47 +1104,127 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-// parseFunctionParameters constructs the slice of func params,
-// exiting early if the list is empty.
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// parseFunctionParameters parses the func params into lit. Parameters
+// may be given a default value, ex: b = 2, after which every parameter
+// needs one, and the last may be a rest parameter, ex: ...rest.
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
+	lit.Parameters = []*ast.Identifier{}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			p.checkDuplicateParameter(lit.Parameters, lit.Rest)
+
+			if !p.peekTokenIs(token.RPAREN) {
+				p.addError(&ParseError{
+					Pos:      p.peekToken.Pos,
+					Expected: []token.TokenType{token.RPAREN},
+					Got:      p.peekToken,
+					Msg:      "the rest parameter must come last",
+				})
+				return false
+			}
+			break
+		}
 
-	if p.peekTokenIs(token.RPAREN) {
-		p.nextToken()
-		return identifiers
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.checkDuplicateParameter(lit.Parameters, ident)
+		lit.Parameters = append(lit.Parameters, ident)
+
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+
+			if lit.Defaults == nil {
+				lit.Defaults = make(map[string]ast.Expression)
+			}
+			lit.Defaults[ident.Value] = p.parseExpression(LOWEST)
+		} else if len(lit.Defaults) > 0 {
+			p.addError(&ParseError{
+				Pos: ident.Pos(),
+				Got: p.curToken,
+				Msg: fmt.Sprintf("parameter %s needs a default value, like the ones before it", ident.Value),
+			})
+			return false
+		}
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return false
+		}
 	}
 
 	p.nextToken()
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	return true
+}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
+// checkDuplicateParameter reports an error if ident is named the
+// same as one of the params before it. Parsing carries on regardless.
+func (p *Parser) checkDuplicateParameter(params []*ast.Identifier, ident *ast.Identifier) {
+	for _, param := range params {
+		if param.Value == ident.Value {
+			p.addError(&ParseError{
+				Pos: ident.Pos(),
+				Got: ident.Token,
+				Msg: fmt.Sprintf("duplicate parameter %s", ident.Value),
+			})
+			return
+		}
+	}
+}
+
+// parseMacroLiteral parses 'macro(<parameters>) { <body> }'. Unlike
+// a func's, a macro's parameters are plain identifiers.
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken, Parameters: []*ast.Identifier{}}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
+		p.checkDuplicateParameter(lit.Parameters, ident)
+		lit.Parameters = append(lit.Parameters, ident)
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
 	}
+	p.nextToken()
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
-	return identifiers
+	lit.Body = p.parseBlockStatement()
+
+	return lit
 }
 
 // parseCallExpression uses the passed function to construct an
 // *ast.CallExpression node.
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseExpressionsList(token.RPAREN)
+	exp.Arguments = p.parseCallArguments()
+	exp.Rparen = p.curToken
 
 	return exp
 }
 
 // parseCallArguments parses a CallExpressions args. Its similar
-// to parseFunctionParameters but returns a slice of ast.Expression
-// instead of *ast.Identifier.
+// to parseExpressionsList, but allows spreading arrays over the
+// arguments.
 func (p *Parser) parseCallArguments() []ast.Expression {
 	args := []ast.Expression{}
 
@@ -506,12 +1234,12 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	args = append(args, p.parseCallArgument())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		args = append(args, p.parseCallArgument())
 	}
 
 	if !p.expectPeek(token.RPAREN) {
@@ -521,6 +1249,21 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 	return args
 }
 
+// parseCallArgument parses a single argument, which
+// may be an array spread over the arguments: ...args
+func (p *Parser) parseCallArgument() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+
+	spread := &ast.SpreadExpression{Token: p.curToken}
+
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+
+	return spread
+}
+
 // parseStringLiteral parses StringLiteral args.
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
@@ -531,6 +1274,7 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
 	array.Elements = p.parseExpressionsList(token.RBRACKET)
+	array.Rbracket = p.curToken
 
 	return array
 }
@@ -562,6 +1306,8 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		return nil
 	}
 
+	hash.Rbrace = p.curToken
+
 	return hash
 }
 
@@ -603,5 +1349,7 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 		return nil
 	}
 
+	exp.Rbracket = p.curToken
+
 	return exp
 }
@@ -42,6 +42,96 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatement(t *testing.T) {
+	l := lexer.New("const max = 10;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+	if !stmt.IsConst() {
+		t.Errorf("stmt.IsConst() is false")
+	}
+	if stmt.Name.Value != "max" {
+		t.Errorf("stmt.Name.Value not 'max'. got=%s", stmt.Name.Value)
+	}
+	if !testLiteralExpression(t, stmt.Value, 10) {
+		return
+	}
+	if stmt.String() != "const max = 10;" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		names    []string
+		rest     string
+		expected string
+	}{
+		{"let [a, b] = xs;", []string{"a", "b"}, "", "let [a, b] = xs;"},
+		{"let [first, ...others] = [1, 2, 3]", []string{"first"}, "others", "let [first, ...others] = [1, 2, 3];"},
+		{"let [...all] = xs;", []string{}, "all", "let [...all] = xs;"},
+		{"const [] = xs;", []string{}, "", "const [] = xs;"},
+		{"let {name, age} = person;", []string{"name", "age"}, "", "let {name, age} = person;"},
+		{"const {x,} = p;", []string{"x"}, "", "const {x} = p;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.DestructuringLetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.DestructuringLetStatement. got=%T", program.Statements[0])
+		}
+
+		var names []*ast.Identifier
+		switch pattern := stmt.Pattern.(type) {
+		case *ast.ArrayPattern:
+			names = pattern.Elements
+			if tt.rest == "" {
+				if pattern.Rest != nil {
+					t.Errorf("pattern.Rest is not nil. got=%s", pattern.Rest)
+				}
+			} else if !testIdentifier(t, pattern.Rest, tt.rest) {
+				return
+			}
+		case *ast.HashPattern:
+			names = pattern.Keys
+		}
+
+		if len(names) != len(tt.names) {
+			t.Fatalf("wrong number of names. want=%d, got=%d", len(tt.names), len(names))
+		}
+		for i, name := range tt.names {
+			if !testIdentifier(t, names[i], name) {
+				return
+			}
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -134,6 +224,75 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0x1F;", 31},
+		{"0XfF;", 255},
+		{"0o17;", 15},
+		{"0b1010;", 10},
+		{"1_000_000;", 1000000},
+		{"0xFF_FF;", 65535},
+		{"0b_1;", 1},
+		{"0755;", 755},
+		{"9223372036854775807;", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5;", 1.5},
+		{"0.25;", 0.25},
+		{"1e3;", 1000},
+		{"2.5E-2;", 0.025},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -142,6 +301,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}{
 		{"!5;", "!", 5},
 		{"-15;", "-", 15},
+		{"~15;", "~", 15},
 		{"!true;", "!", true},
 		{"!false;", "!", false},
 	}
@@ -189,6 +349,17 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"true && false", true, "&&", false},
+		{"true || false", true, "||", false},
+		{"5 % 5;", 5, "%", 5},
+		{"5 ** 5;", 5, "**", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
@@ -336,6 +507,54 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a == b && !c || d < e + 1",
+			"(((a == b) && (!c)) || (d < (e + 1)))",
+		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"-a ** b",
+			"(-(a ** b))",
+		},
+		{
+			"a ** b ** c",
+			"(a ** (b ** c))",
+		},
+		{
+			"a * b ** -c",
+			"(a * (b ** (-c)))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b << c + d",
+			"(a & (b << (c + d)))",
+		},
+		{
+			"a == b | c && d",
+			"((a == (b | c)) && d)",
+		},
+		{
+			"~a & b",
+			"((~a) & b)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -351,6 +570,91 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   string
+		operator string
+		value    string
+	}{
+		{"x = 5;", "x", "=", "5"},
+		{"x += y * 2;", "x", "+=", "(y * 2)"},
+		{"x -= y || z;", "x", "-=", "(y || z)"},
+		{"x *= 3", "x", "*=", "3"},
+		{"x /= 3", "x", "/=", "3"},
+		{"x = y = 1;", "x", "=", "y = 1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.AssignExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.AssignExpression. got=%T", stmt.Expression)
+		}
+
+		if !testIdentifier(t, exp.Target, tt.target) {
+			return
+		}
+
+		if exp.Operator != tt.operator {
+			t.Errorf("exp.Operator is not %q. got=%q", tt.operator, exp.Operator)
+		}
+
+		if exp.Value.String() != tt.value {
+			t.Errorf("exp.Value is not %q. got=%q", tt.value, exp.Value.String())
+		}
+	}
+}
+
+func TestIndexAssignExpression(t *testing.T) {
+	input := `xs[i + 1] += h["k"] = 2`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.AssignExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.AssignExpression. got=%T", stmt.Expression)
+	}
+
+	target, ok := exp.Target.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp.Target is not ast.IndexExpression. got=%T", exp.Target)
+	}
+
+	if !testIdentifier(t, target.Left, "xs") {
+		return
+	}
+
+	if !testInfixExpression(t, target.Index, "i", "+", 1) {
+		return
+	}
+
+	if _, ok := exp.Value.(*ast.AssignExpression); !ok {
+		t.Fatalf("exp.Value is not ast.AssignExpression. got=%T", exp.Value)
+	}
+
+	if exp.String() != `(xs[(i + 1)]) += (h[k]) = 2` {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -489,6 +793,194 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { if (x) { break; } continue }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d\n", len(stmt.Body.Statements))
+	}
+
+	ifStmt, ok := stmt.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", stmt.Body.Statements[0])
+	}
+
+	ifExp, ok := ifStmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.IfExpression. got=%T", ifStmt.Expression)
+	}
+
+	if _, ok := ifExp.Consequence.Statements[0].(*ast.BreakStatement); !ok {
+		t.Errorf("consequence is not ast.BreakStatement. got=%T", ifExp.Consequence.Statements[0])
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Errorf("Statements[1] is not ast.ContinueStatement. got=%T", stmt.Body.Statements[1])
+	}
+
+	if stmt.String() != "while(x < y) ifx break;continue;" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestForInStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		key      string
+		value    string
+		expected string
+	}{
+		{"for (x in xs) { x }", "", "x", "for(x in xs) x"},
+		{"for (k, v in {}) { k; v; }", "k", "v", "for(k, v in {}) kv"},
+		{"for (i in range(1 + 2)) { }", "", "i", "for(i in range((1 + 2))) "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ForInStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ForInStatement. got=%T", program.Statements[0])
+		}
+
+		if tt.key == "" {
+			if stmt.Key != nil {
+				t.Errorf("stmt.Key is not nil. got=%s", stmt.Key)
+			}
+		} else if !testIdentifier(t, stmt.Key, tt.key) {
+			return
+		}
+
+		if !testIdentifier(t, stmt.Value, tt.value) {
+			return
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		param    string
+		finally  bool
+		expected string
+	}{
+		{"try { f() } catch (e) { e }", "e", false, "try f() catch (e) e"},
+		{"try { f() } finally { g() }", "", true, "try f() finally g()"},
+		{"try { } catch (err) { } finally { g() }", "err", true, "try  catch (err)  finally g()"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.TryStatement. got=%T", program.Statements[0])
+		}
+
+		if tt.param == "" {
+			if stmt.Param != nil || stmt.Catch != nil {
+				t.Errorf("stmt has a catch block. got=%s", stmt.Param)
+			}
+		} else if !testIdentifier(t, stmt.Param, tt.param) {
+			return
+		}
+
+		if (stmt.Finally != nil) != tt.finally {
+			t.Errorf("stmt.Finally wrong. want set=%t, got=%v", tt.finally, stmt.Finally)
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	input := `throw "bad " + x;`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+
+	if _, ok := stmt.Value.(*ast.InfixExpression); !ok {
+		t.Errorf("stmt.Value is not ast.InfixExpression. got=%T", stmt.Value)
+	}
+
+	if stmt.String() != "throw (bad  + x);" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestBlockStatementSemicolons(t *testing.T) {
+	tests := []string{
+		"while (x) { f() }; puts(1)",
+		"for (x in xs) { f() }; puts(1)",
+		"try { f() } catch (e) { e }; puts(1)",
+		"try { f() } finally { g() };; puts(1)",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 2 {
+			t.Fatalf("%q: program.Statements does not contain %d statements. got=%d\n", input, 2, len(program.Statements))
+		}
+
+		if program.Statements[1].String() != "puts(1)" {
+			t.Errorf("%q: second statement wrong. got=%q", input, program.Statements[1].String())
+		}
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -536,6 +1028,53 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d\n", len(macro.Parameters))
+	}
+
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d\n", len(macro.Body.Statements))
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T", macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+
+	if macro.String() != "macro(x, y) (x + y)" {
+		t.Errorf("macro.String() wrong. got=%q", macro.String())
+	}
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -566,6 +1105,61 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestFunctionDefaultsAndRest(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedParams   []string
+		expectedDefaults map[string]string
+		expectedRest     string
+		expected         string
+	}{
+		{"fn(a, b = 2) {}", []string{"a", "b"}, map[string]string{"b": "2"}, "", "fn(a, b = 2) "},
+		{"fn(a = x + 1, b = a) {}", []string{"a", "b"}, map[string]string{"a": "(x + 1)", "b": "a"}, "", "fn(a = (x + 1), b = a) "},
+		{"fn(...rest) {}", []string{}, map[string]string{}, "rest", "fn(...rest) "},
+		{"fn(a, b = 2, ...rest) {}", []string{"a", "b"}, map[string]string{"b": "2"}, "rest", "fn(a, b = 2, ...rest) "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("length parameters wrong. want %d, got=%d\n",
+				len(tt.expectedParams), len(function.Parameters))
+		}
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+
+		if len(function.Defaults) != len(tt.expectedDefaults) {
+			t.Errorf("length defaults wrong. want %d, got=%d\n",
+				len(tt.expectedDefaults), len(function.Defaults))
+		}
+		for name, expected := range tt.expectedDefaults {
+			if def := function.Defaults[name]; def == nil || def.String() != expected {
+				t.Errorf("default of %s wrong. want=%q, got=%v", name, expected, def)
+			}
+		}
+
+		if tt.expectedRest == "" {
+			if function.Rest != nil {
+				t.Errorf("function.Rest is not nil. got=%s", function.Rest)
+			}
+		} else {
+			testIdentifier(t, function.Rest, tt.expectedRest)
+		}
+
+		if function.String() != tt.expected {
+			t.Errorf("function.String() wrong. want=%q, got=%q", tt.expected, function.String())
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -622,6 +1216,11 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 			expectedIdent: "add",
 			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
 		},
+		{
+			input:         "add(...xs, 1, ...[2 + 3]);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"...xs", "1", "...[(2 + 3)]"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -717,7 +1316,7 @@ func TestParsingIndexExpressions(t *testing.T) {
 }
 
 func TestParsingHashLiteralStringKeys(t *testing.T) {
-	input := `{"one": 1, "two": 2, "three": 3}"`
+	input := `{"one": 1, "two": 2, "three": 3}`
 
 	l := lexer.New(input)
 	p := New(l)
@@ -978,3 +1577,139 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 
 	return true
 }
+
+func TestNodePositions(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b;
+};
+add(1, [2, 3][0]);`
+
+	l := lexer.NewWithFilename("test.mk", input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	fn := let.Value.(*ast.FunctionLiteral)
+	infix := fn.Body.Statements[0].(*ast.ExpressionStatement).Expression
+	call := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	index := call.Arguments[1]
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{program, "test.mk:1:1-test.mk:4:18"},
+		{let, "test.mk:1:1-test.mk:3:2"},
+		{fn, "test.mk:1:11-test.mk:3:2"},
+		{fn.Body, "test.mk:1:20-test.mk:3:2"},
+		{infix, "test.mk:2:3-test.mk:2:8"},
+		{call, "test.mk:4:1-test.mk:4:18"},
+		{index, "test.mk:4:8-test.mk:4:17"},
+	}
+
+	for i, tt := range tests {
+		span := fmt.Sprintf("%s-%s", tt.node.Pos(), tt.node.End())
+		if span != tt.expected {
+			t.Errorf("tests[%d] - %T span wrong. expected=%q, got=%q", i, tt.node, tt.expected, span)
+		}
+	}
+}
+
+func TestGroupedExpressionSpans(t *testing.T) {
+	tests := []struct {
+		input    string
+		operands []string // the source of each operand of the outermost expression
+	}{
+		{"(2 ** 3) ** 2", []string{"(2 ** 3)", "2"}},
+		{"2 ** (3 ** 2)", []string{"2", "(3 ** 2)"}},
+		{"-(1 + 2)", []string{"(1 + 2)"}},
+		{"((x))", []string{"(x)"}},
+		{"(a)[0]", []string{"(a)", "0"}},
+		{"(f)(1)", []string{"(f)", "1"}},
+		{"(x) = 1", []string{"(x)", "1"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		text := func(node ast.Node) string {
+			return tt.input[node.Pos().Offset:node.End().Offset]
+		}
+
+		exp := program.Statements[0].(*ast.ExpressionStatement).Expression
+		if text(exp) != tt.input {
+			t.Errorf("%q: %T span wrong. got=%q", tt.input, exp, text(exp))
+		}
+
+		var operands []ast.Expression
+		switch exp := exp.(type) {
+		case *ast.InfixExpression:
+			operands = []ast.Expression{exp.Left, exp.Right}
+		case *ast.PrefixExpression:
+			operands = []ast.Expression{exp.Right}
+		case *ast.ParenExpression:
+			operands = []ast.Expression{exp.Expression}
+		case *ast.IndexExpression:
+			operands = []ast.Expression{exp.Left, exp.Index}
+		case *ast.CallExpression:
+			operands = append([]ast.Expression{exp.Function}, exp.Arguments...)
+		case *ast.AssignExpression:
+			operands = []ast.Expression{exp.Target, exp.Value}
+		}
+
+		if len(operands) != len(tt.operands) {
+			t.Fatalf("%q: wrong number of operands. want=%d, got=%d", tt.input, len(tt.operands), len(operands))
+		}
+		for i, operand := range operands {
+			if text(operand) != tt.operands[i] {
+				t.Errorf("%q: operand %d span wrong. want=%q, got=%q", tt.input, i, tt.operands[i], text(operand))
+			}
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// add sums its arguments.
+let add = fn(a, /* the other one */ b) {
+  a + b; // no return needed
+};`
+
+	l := lexer.New(input)
+	l.EmitComments(true)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	if program.String() != "let add = fn<add>(a, b) (a + b);" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+
+	expected := []struct {
+		text string
+		pos  string
+	}{
+		{"// add sums its arguments.", "1:1"},
+		{"/* the other one */", "2:17"},
+		{"// no return needed", "3:10"},
+	}
+
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("program.Comments does not contain %d comments. got=%d", len(expected), len(program.Comments))
+	}
+
+	for i, tt := range expected {
+		comment := program.Comments[i]
+		if comment.String() != tt.text {
+			t.Errorf("comments[%d] text wrong. expected=%q, got=%q", i, tt.text, comment.String())
+		}
+		if comment.Pos().String() != tt.pos {
+			t.Errorf("comments[%d] position wrong. expected=%q, got=%q", i, tt.pos, comment.Pos())
+		}
+	}
+}
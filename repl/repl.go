@@ -3,10 +3,12 @@ package repl
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/adamwoolhether/monkeyLang/compiler"
+	"github.com/adamwoolhether/monkeyLang/evaluator"
 	"github.com/adamwoolhether/monkeyLang/lexer"
 	"github.com/adamwoolhether/monkeyLang/object"
 	"github.com/adamwoolhether/monkeyLang/parser"
@@ -33,6 +35,7 @@ func Start(in io.Reader, out io.Writer) {
 
 	constants := []object.Object{}
 	globals := make([]object.Object, vm.GlobalsSize)
+	macroEnv := object.NewEnvironment()
 
 	symbolTable := compiler.NewSymbolTable()
 	for i, v := range object.Builtins {
@@ -53,12 +56,19 @@ func Start(in io.Reader, out io.Writer) {
 
 		program := p.ParseProgram()
 		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+			printParserErrors(out, line, p.Errors())
+			continue
+		}
+
+		evaluator.DefineMacros(program, macroEnv)
+		expanded, err := evaluator.ExpandMacros(program, macroEnv)
+		if err != nil {
+			fmt.Fprintf(out, "Whoops! Macro expansion failed:\n %s\n", err)
 			continue
 		}
 
 		comp := compiler.NewWithState(symbolTable, constants)
-		err := comp.Compile(program)
+		err = comp.Compile(expanded)
 		if err != nil {
 			fmt.Fprintf(out, "Whoops! Compilation failed:\n %s\n", err)
 			continue
@@ -71,20 +81,27 @@ func Start(in io.Reader, out io.Writer) {
 		err = machine.Run()
 		if err != nil {
 			fmt.Fprintf(out, "Whoops! Executing bytecode failed:\n %s\n", err)
+
+			var rtErr *vm.RuntimeError
+			if errors.As(err, &rtErr) {
+				fmt.Fprintf(out, "%s\n", rtErr.Trace)
+			}
 			continue
 		}
 
+		// A line that only defines macros leaves nothing to run.
 		lastPopped := machine.LastPoppedStackElem()
+		if lastPopped == nil {
+			continue
+		}
 		io.WriteString(out, lastPopped.Inspect())
 		io.WriteString(out, "\n")
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
+func printParserErrors(out io.Writer, source string, errors []*parser.ParseError) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Whoops! We ran into some monkey business here!\n")
 	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
-	}
+	parser.RenderErrors(out, source, errors)
 }
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/adamwoolhether/monkeyLang/ast"
+	"github.com/adamwoolhether/monkeyLang/compiler"
+	"github.com/adamwoolhether/monkeyLang/evaluator"
+	"github.com/adamwoolhether/monkeyLang/lexer"
+	"github.com/adamwoolhether/monkeyLang/object"
+	"github.com/adamwoolhether/monkeyLang/parser"
+	"github.com/adamwoolhether/monkeyLang/vm"
+)
+
+// argsName is the global binding that holds a script's arguments.
+const argsName = "args"
+
+// stdinName stands in for the filename of a script read from standard input.
+const stdinName = "<stdin>"
+
+const runUsage = `usage: monkey run [-engine vm|eval] <script> [arguments]
+
+Run runs a script, binding the arguments following it to the
+global args, as an array of strings. The script's exit status
+is 1 if it fails to parse, compile or run.
+
+`
+
+func runCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("run", runUsage, stderr)
+	engine := flags.String("engine", "vm", "use 'vm' or 'eval'")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	if *engine != "vm" && *engine != "eval" {
+		fmt.Fprintf(stderr, "monkey run: unknown engine %q, use 'vm' or 'eval'\n", *engine)
+		return exitUsage
+	}
+
+	program, status := loadScript(flags.Arg(0), stdin, stderr)
+	if program == nil {
+		return status
+	}
+
+	scriptArgs := &object.Array{Elements: []object.Object{}}
+	for _, arg := range flags.Args()[1:] {
+		scriptArgs.Elements = append(scriptArgs.Elements, &object.String{Value: arg})
+	}
+
+	if *engine == "eval" {
+		env := object.NewEnvironment()
+		env.Set(argsName, scriptArgs)
+
+		if err, ok := evalScript(program, env).(*object.Error); ok {
+			printRuntimeError(stderr, err.Message, err.Trace)
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	bytecode, globals, err := compileScript(program, scriptArgs)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	machine := vm.NewWithGlobalsStore(bytecode, globals)
+	if err := machine.Run(); err != nil {
+		var rtErr *vm.RuntimeError
+		if errors.As(err, &rtErr) {
+			printRuntimeError(stderr, rtErr.Msg, rtErr.Trace)
+		} else {
+			fmt.Fprintf(stderr, "error: %s\n", err)
+		}
+		return exitFailure
+	}
+
+	return exitOK
+}
+
+// evalScript evaluates program in env. Like vm.Run, it turns the Go
+// panics a bug in the evaluator may cause into runtime errors.
+func evalScript(program *ast.Program, env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = &object.Error{Message: fmt.Sprintf("internal error: %v", r)}
+		}
+	}()
+
+	return evaluator.Eval(program, env)
+}
+
+// printRuntimeError reports an error that a script didn't catch.
+func printRuntimeError(stderr io.Writer, msg string, trace object.StackTrace) {
+	fmt.Fprintf(stderr, "error: %s\n", msg)
+	if len(trace) > 0 {
+		fmt.Fprintln(stderr, trace)
+	}
+}
+
+const compileUsage = `usage: monkey compile <script>
+
+Compile compiles a script to bytecode without running it,
+reporting any errors that keep it from compiling.
+`
+
+func compileCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	program, status := loadScriptArg("compile", compileUsage, args, stdin, stderr)
+	if program == nil {
+		return status
+	}
+
+	if _, _, err := compileScript(program, &object.Array{}); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	return exitOK
+}
+
+const disasmUsage = `usage: monkey disasm <script>
+
+Disasm prints the bytecode a script compiles to: its constants,
+followed by the instructions of the main program and of each
+compiled function among the constants.
+`
+
+func disasmCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	program, status := loadScriptArg("disasm", disasmUsage, args, stdin, stderr)
+	if program == nil {
+		return status
+	}
+
+	bytecode, _, err := compileScript(program, &object.Array{})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	disassemble(stdout, bytecode)
+	return exitOK
+}
+
+// loadScriptArg parses the args of a command that takes a single
+// script and no flags, and loads the script.
+func loadScriptArg(name, cmdUsage string, args []string, stdin io.Reader, stderr io.Writer) (*ast.Program, int) {
+	flags := newFlagSet(name, cmdUsage, stderr)
+	if err := flags.Parse(args); err != nil {
+		return nil, exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return nil, exitUsage
+	}
+
+	return loadScript(flags.Arg(0), stdin, stderr)
+}
+
+// loadScript reads the script at path, or standard input if path is
+// "-", parses it and expands its macros. If that fails, the errors
+// are reported to stderr and the program is nil, along with the exit
+// status to return.
+func loadScript(path string, stdin io.Reader, stderr io.Writer) (*ast.Program, int) {
+	name := path
+
+	var src []byte
+	var err error
+	if path == "-" {
+		name = stdinName
+		src, err = io.ReadAll(stdin)
+	} else {
+		src, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return nil, exitUsage
+	}
+
+	l := lexer.NewWithFilename(name, string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		parser.RenderErrors(stderr, string(src), p.Errors())
+		return nil, exitFailure
+	}
+
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, macroEnv)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return nil, exitFailure
+	}
+
+	return expanded.(*ast.Program), exitOK
+}
+
+// compileScript compiles program with args bound to the global
+// args, returning the globals store the bytecode needs to run.
+func compileScript(program *ast.Program, args *object.Array) (*compiler.Bytecode, []object.Object, error) {
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	globals := make([]object.Object, vm.GlobalsSize)
+	globals[symbolTable.Define(argsName).Index] = args
+
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	if err := comp.Compile(program); err != nil {
+		return nil, nil, err
+	}
+
+	return comp.Bytecode(), globals, nil
+}
+
+// disassemble writes bytecode's constants to w, followed by the
+// instructions of the main program and of each compiled function.
+func disassemble(w io.Writer, bytecode *compiler.Bytecode) {
+	fmt.Fprintln(w, "constants:")
+	for i, c := range bytecode.Constants {
+		fmt.Fprintf(w, "%04d %s %s\n", i, c.Type(), describeConstant(c))
+	}
+
+	fmt.Fprintf(w, "\n%s:\n%s", object.MainFunctionName, bytecode.Instructions)
+
+	for i, c := range bytecode.Constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			fmt.Fprintf(w, "\n%s (constant %d):\n%s", object.FunctionName(fn.Name), i, fn.Instructions)
+		}
+	}
+}
+
+// describeConstant renders a constant for disasm's listing.
+func describeConstant(c object.Object) string {
+	switch c := c.(type) {
+	case *object.String:
+		return strconv.Quote(c.Value)
+	case *object.CompiledFunction:
+		return fmt.Sprintf("%s params=%d locals=%d", object.FunctionName(c.Name), c.NumParameters, c.NumLocals)
+	default:
+		return c.Inspect()
+	}
+}
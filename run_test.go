@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adamwoolhether/monkeyLang/ast"
+	"github.com/adamwoolhether/monkeyLang/object"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir, "script.mk", `
+let unless = macro(cond, body) { quote(if (!(unquote(cond))) { unquote(body) }) };
+unless(len(args) == 0, args[0]);
+let fail = fn() { throw "boom: " + args[1] };
+if (len(args) > 1) { fail() }
+`)
+
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedStatus int
+		expectedStderr string
+	}{
+		{[]string{"run", script}, "", exitOK, ""},
+		{[]string{"run", "-engine", "eval", script, "a"}, "", exitOK, ""},
+		{[]string{script, "a", "b"}, "", exitFailure, "error: boom: b\n    at fail (" + script + ":4:19)\n    at <main> (" + script + ":5:22)\n"},
+		{[]string{"-engine=eval", script, "a", "b"}, "", exitFailure, "error: boom: b\n"},
+		{[]string{"run", "-"}, "let x = 1; x", exitOK, ""},
+		{[]string{"-"}, "let x = ;", exitFailure, "<stdin>:1:9: unexpected ';'\n"},
+		{[]string{"-"}, "x = 1", exitFailure, "<stdin>:1:1: undefined variable x\n"},
+		{[]string{"-"}, "let m = macro() { 1 }; m()", exitFailure, "<stdin>:1:24: macro m returned INTEGER, not a quote\n"},
+		{[]string{"-engine=eval", "-"}, "1 + true", exitFailure, "error: type mismatch: INTEGER + BOOLEAN\n"},
+		{[]string{"-engine=eval", "-"}, "let x = if (true) { let a = 1; }; puts(x)", exitOK, ""},
+		{[]string{"-"}, "while (true) { 1 + if (true) { break } else { 2 } }", exitFailure, "<stdin>:1:32: break can't be part of an expression\n"},
+		{[]string{"-engine=eval", "-"}, "while (true) { 1 + if (true) { break } else { 2 } }", exitFailure, "<stdin>:1:32: break can't be part of an expression\n"},
+		{[]string{"-engine=eval", "-"}, `if (false) { break; } puts("ran")`, exitFailure, "<stdin>:1:14: break outside of loop\n"},
+		{[]string{"-engine=eval", "-"}, "let f = fn(n) { f(n + 1) }; f(0)", exitFailure, "error: call stack overflow: exceeded 1024 nested calls\n"},
+		{[]string{"-"}, "puts(quote(1 + unquote(2)))", exitOK, ""},
+		{[]string{"run"}, "", exitUsage, "usage: monkey run"},
+		{[]string{"run", "-engine", "jit", script}, "", exitUsage, "monkey run: unknown engine \"jit\""},
+		{[]string{filepath.Join(dir, "missing.mk")}, "", exitUsage, "monkey: open "},
+		{[]string{"compile", script}, "", exitOK, ""},
+		{[]string{"compile", "-"}, "break;", exitFailure, "<stdin>:1:1: break outside of loop\n"},
+		{[]string{"compile", script, "extra"}, "", exitUsage, "usage: monkey compile"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		status := dispatch(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+		if status != tt.expectedStatus {
+			t.Errorf("monkey %v: wrong exit status. want=%d, got=%d (stderr: %q)",
+				tt.args, tt.expectedStatus, status, stderr.String())
+		}
+		if !strings.HasPrefix(stderr.String(), tt.expectedStderr) || tt.expectedStderr == "" && stderr.Len() != 0 {
+			t.Errorf("monkey %v: wrong stderr. want prefix %q, got=%q", tt.args, tt.expectedStderr, stderr.String())
+		}
+	}
+}
+
+func TestEvalScriptRecovers(t *testing.T) {
+	// An infix expression missing its operands, which
+	// the parser never produces, makes the evaluator panic.
+	program := &ast.Program{Statements: []ast.Statement{
+		&ast.ExpressionStatement{Expression: &ast.InfixExpression{Operator: "+"}},
+	}}
+
+	err, ok := evalScript(program, object.NewEnvironment()).(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned")
+	}
+	if !strings.HasPrefix(err.Message, "internal error: ") {
+		t.Errorf("wrong error message. want prefix %q, got=%q", "internal error: ", err.Message)
+	}
+}
+
+func TestDisasm(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	input := `let add = fn(a, b) { a + b }; add(1, "two")`
+
+	status := dispatch([]string{"disasm", "-"}, strings.NewReader(input), &stdout, &stderr)
+	if status != exitOK {
+		t.Fatalf("wrong exit status. want=%d, got=%d (stderr: %q)", exitOK, status, stderr.String())
+	}
+
+	expected := `constants:
+0000 COMPILED_FUNCTION_OBJ add params=2 locals=2
+0001 INTEGER 1
+0002 STRING "two"
+
+<main>:
+0000 OpClosure 0 0
+0004 OpSetGlobal 1
+0007 OpGetGlobal 1
+0010 OpConstant 1
+0013 OpConstant 2
+0016 OpCall 2
+0018 OpPop
+
+add (constant 0):
+0000 OpGetLocal 0
+0002 OpGetLocal 1
+0004 OpAdd
+0005 OpReturnValue
+`
+	if stdout.String() != expected {
+		t.Errorf("wrong disassembly.\nwant=%q\ngot= %q", expected, stdout.String())
+	}
+}
+
+func TestFmtCommand(t *testing.T) {
+	dir := t.TempDir()
+	formatted := writeScript(t, dir, "formatted.mk", "let x = 1;\n")
+	messy := writeScript(t, dir, "messy.mk", "let   x=1")
+	writeScript(t, dir, "notes.txt", "not monkey")
+
+	var stdout, stderr bytes.Buffer
+	if status := dispatch([]string{"fmt", "-check", dir}, nil, &stdout, &stderr); status != 1 {
+		t.Errorf("fmt -check: wrong exit status. want=1, got=%d (stderr: %q)", status, stderr.String())
+	}
+	if stdout.String() != messy+"\n" {
+		t.Errorf("fmt -check: wrong files listed. want=%q, got=%q", messy+"\n", stdout.String())
+	}
+
+	stdout.Reset()
+	if status := dispatch([]string{"fmt", "-w", dir}, nil, &stdout, &stderr); status != 0 {
+		t.Errorf("fmt -w: wrong exit status. want=0, got=%d (stderr: %q)", status, stderr.String())
+	}
+	for _, path := range []string{formatted, messy} {
+		if src, _ := os.ReadFile(path); string(src) != "let x = 1;\n" {
+			t.Errorf("fmt -w: %s not formatted. got=%q", path, src)
+		}
+	}
+
+	stdout.Reset()
+	if status := dispatch([]string{"fmt"}, strings.NewReader("puts( 1 )"), &stdout, &stderr); status != 0 {
+		t.Errorf("fmt: wrong exit status. want=0, got=%d (stderr: %q)", status, stderr.String())
+	}
+	if stdout.String() != "puts(1);\n" {
+		t.Errorf("fmt: wrong output. want=%q, got=%q", "puts(1);\n", stdout.String())
+	}
+}
+
+func writeScript(t *testing.T, dir, name, src string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
@@ -1,22 +1,60 @@
 // Package token defines the tokens for use by the lexer.
 package token
 
+import "fmt"
+
 // TokenType distinguishes the unique token types to represent the source code.
 type TokenType string
 
-// Token contains the type of token and its value.
+// Token contains the type of token and its value, along with
+// the span of source code it was read from.
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // position of the token's first character
+	End     Position // position immediately after the token's last character
+}
+
+// Position describes a location in Monkey source code. Line and
+// Column are 1-based, and Column counts characters rather than bytes.
+// The zero value is an invalid position, used for synthesized tokens.
+type Position struct {
+	Filename string // filename, if any
+	Offset   int    // byte offset into the source, starting at 0
+	Line     int    // line number, starting at 1
+	Column   int    // column number, starting at 1
+}
+
+// IsValid reports whether the position was set by the lexer.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+// String returns the position in the form "file:line:column",
+// omitting the filename if it's empty. An invalid position
+// is rendered as "-".
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT" // only emitted when the lexer is asked to keep comments
 	
 	// Identifiers + literals
 	IDENT = "IDENT" // add, foobar, x, y, ...
 	INT   = "INT"   // 1234567890
+	FLOAT = "FLOAT" // 1.5, 1e-3
 	
 	// Operators
 	ASSIGN   = "="
@@ -25,17 +63,38 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
+	POWER    = "**"
+	
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	
+	AMPERSAND = "&"
+	PIPE      = "|"
+	CARET     = "^"
+	TILDE     = "~"
+	LSHIFT    = "<<"
+	RSHIFT    = ">>"
 	
 	LT = "<"
 	GT = ">"
 	
+	LT_EQ = "<="
+	GT_EQ = ">="
+	
 	EQ     = "=="
 	NOT_EQ = "!="
 	
+	AND = "&&"
+	OR  = "||"
+	
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	ELLIPSIS  = "..."
 	
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -47,11 +106,22 @@ const (
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	FOR      = "FOR"
+	IN       = "IN"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
+	MACRO    = "MACRO"
 	
 	// Data Types
 	STRING = "STRING"
@@ -60,13 +130,24 @@ const (
 // keywords holds our language keywords, to separate them
 // from user-defined identifiers.
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"const":    CONST,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"for":      FOR,
+	"in":       IN,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
+	"macro":    MACRO,
 }
 
 // LookupIdent checks keywords to see if the user-given identifier is a language
@@ -0,0 +1,51 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/adamwoolhether/monkeyLang/object"
+)
+
+// cell boxes a variable once a closure captures it, so that assignments
+// made by either the closure or the function defining the variable are
+// seen by both. Cells only ever live in local variable slots and in a
+// closure's free variables: loading a variable unboxes its value.
+type cell struct {
+	value object.Object
+}
+
+func (c *cell) Type() object.ObjectType { return "CELL" }
+func (c *cell) Inspect() string         { return fmt.Sprintf("cell(%s)", c.value.Inspect()) }
+
+// load returns the value held by a variable slot.
+func load(slot object.Object) object.Object {
+	if c, ok := slot.(*cell); ok {
+		return c.value
+	}
+
+	return slot
+}
+
+// store assigns to the variable in a slot,
+// writing through to its cell if it was captured.
+func store(slot *object.Object, value object.Object) {
+	if c, ok := (*slot).(*cell); ok {
+		c.value = value
+		return
+	}
+
+	*slot = value
+}
+
+// capture boxes the variable slot in a cell,
+// unless it already was, and returns the cell.
+func capture(slot *object.Object) *cell {
+	if c, ok := (*slot).(*cell); ok {
+		return c
+	}
+
+	c := &cell{value: *slot}
+	*slot = c
+
+	return c
+}
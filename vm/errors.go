@@ -0,0 +1,131 @@
+package vm
+
+import (
+	"errors"
+
+	"github.com/adamwoolhether/monkeyLang/code"
+	"github.com/adamwoolhether/monkeyLang/object"
+)
+
+// RuntimeError describes a failure while running bytecode. Op and IP
+// locate the instruction that failed within the instructions of the
+// function being executed, and Trace lists the calls that led to it.
+// Every error returned by VM.Run is a *RuntimeError, including
+// unexpected Go panics, which are recovered. Value holds the value
+// of a throw statement, and is nil for other errors.
+type RuntimeError struct {
+	Op    code.Opcode
+	IP    int
+	Msg   string
+	Trace object.StackTrace
+	Value object.Object
+
+	internal bool // Whether the error is a recovered panic, which can't be caught.
+}
+
+// Error returns the error's message.
+func (e *RuntimeError) Error() string {
+	return e.Msg
+}
+
+// OpName returns the human-readable name of the failing opcode.
+func (e *RuntimeError) OpName() string {
+	def, err := code.Lookup(byte(e.Op))
+	if err != nil {
+		return "unknown"
+	}
+
+	return def.Name
+}
+
+// newRuntimeError wraps err as a *RuntimeError for the instruction
+// at ip, unless it's already one, capturing the current stack trace.
+func (vm *VM) newRuntimeError(op code.Opcode, ip int, err error) *RuntimeError {
+	var rtErr *RuntimeError
+	if errors.As(err, &rtErr) {
+		return rtErr
+	}
+
+	return &RuntimeError{Op: op, IP: ip, Msg: err.Error(), Trace: vm.stackTrace()}
+}
+
+// throw returns the error raised by throwing val. An error
+// rethrown after a finally block ran keeps its original trace.
+func (vm *VM) throw(op code.Opcode, ip int, val object.Object) *RuntimeError {
+	if err, ok := val.(*object.Error); ok {
+		trace := err.Trace
+		if trace == nil {
+			trace = vm.stackTrace()
+		}
+
+		return &RuntimeError{Op: op, IP: ip, Msg: err.Message, Trace: trace, Value: err.Value}
+	}
+
+	return &RuntimeError{Op: op, IP: ip, Msg: object.ThrowMessage(val), Trace: vm.stackTrace(), Value: val}
+}
+
+// handle unwinds the stack to the innermost active exception handler,
+// resuming execution at its target with err pushed onto the stack. It
+// reports false if there's no such handler, or err can't be caught.
+func (vm *VM) handle(err error) bool {
+	var rtErr *RuntimeError
+	if !errors.As(err, &rtErr) || rtErr.internal {
+		return false
+	}
+
+	for i := vm.framesIndex - 1; i >= 0; i-- {
+		frame := vm.frames[i]
+		if len(frame.handlers) == 0 {
+			continue
+		}
+
+		h := frame.handlers[len(frame.handlers)-1]
+		frame.handlers = frame.handlers[:len(frame.handlers)-1]
+
+		vm.framesIndex = i + 1
+		vm.sp = h.sp
+		frame.ip = h.Target - 1
+
+		caught := &object.Error{Message: rtErr.Msg, Trace: rtErr.Trace, Value: rtErr.Value}
+		if !h.Catch {
+			vm.stack[vm.sp] = caught
+			vm.sp++
+			return true
+		}
+
+		// A catch block only sees the frames from where the
+		// error was raised up to its own, which is the i-th.
+		caught.Trace = caught.Trace[:len(caught.Trace)-i]
+		vm.stack[vm.sp] = object.ErrorHash(caught)
+		vm.sp++
+		return true
+	}
+
+	return false
+}
+
+// stackTrace walks the active frames, starting with the innermost one.
+// Each frame is reported at the source position of the instruction it's
+// executing, or where its function was defined if that's unknown.
+func (vm *VM) stackTrace() object.StackTrace {
+	trace := make(object.StackTrace, 0, vm.framesIndex)
+
+	for i := vm.framesIndex - 1; i >= 0; i-- {
+		frame := vm.frames[i]
+		fn := frame.cl.Fn
+
+		name := object.FunctionName(fn.Name)
+		if i == 0 {
+			name = object.MainFunctionName
+		}
+
+		pos, ok := fn.SourceMap.Lookup(frame.ip)
+		if !ok {
+			pos = fn.Pos
+		}
+
+		trace = append(trace, object.StackFrame{Function: name, Pos: pos})
+	}
+
+	return trace
+}
@@ -11,6 +11,15 @@ type Frame struct {
 	cl          *object.Closure // The compiled func referenced by the frame.
 	ip          int             // The IP for this frame/function.
 	basePointer int             // Points to the bottom of the stack of current call frame.
+	numArgs     int             // How many arguments the func was called with.
+	handlers    []handler       // The exception handlers activated by OpTry, innermost last.
+}
+
+// handler is an exception handler active in a frame, along with
+// the stack pointer to restore when it handles an error.
+type handler struct {
+	code.Handler
+	sp int
 }
 
 func NewFrame(cl *object.Closure, basePointer int) *Frame {
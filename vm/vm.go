@@ -2,7 +2,9 @@ package vm
 
 import (
 	"fmt"
+	"math"
 
+	"github.com/adamwoolhether/monkeyLang/ast"
 	"github.com/adamwoolhether/monkeyLang/code"
 	"github.com/adamwoolhether/monkeyLang/compiler"
 	"github.com/adamwoolhether/monkeyLang/object"
@@ -44,7 +46,12 @@ type VM struct {
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
-	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainFn := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		NumLocals:    bytecode.NumLocals,
+		SourceMap:    bytecode.SourceMap,
+		Handlers:     bytecode.Handlers,
+	}
 	mainClosure := &object.Closure{Fn: mainFn}
 	mainFrame := NewFrame(mainClosure, 0)
 
@@ -55,7 +62,7 @@ func New(bytecode *compiler.Bytecode) *VM {
 	return &VM{
 		constants:   bytecode.Constants,
 		stack:       make([]object.Object, StackSize),
-		sp:          0,
+		sp:          bytecode.NumLocals,
 		globals:     make([]object.Object, GlobalsSize),
 		frames:      frames,
 		framesIndex: 1,
@@ -69,15 +76,40 @@ func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
 	return vm
 }
 
-// Run turns VM into a virtual machine. It contains the heartbeat,
-// main loop, and fetch-decode-execute cycle.
+// Run executes the bytecode. Any error it returns is a *RuntimeError.
+// A runtime error raised while a try statement's exception handler is
+// active is handed to the handler instead, after unwinding the frames
+// above the handler's own, and execution resumes.
 func (vm *VM) Run() error {
+	for {
+		err := vm.run()
+		if err == nil || !vm.handle(err) {
+			return err
+		}
+	}
+}
+
+// run turns VM into a virtual machine. It contains the heartbeat,
+// main loop, and fetch-decode-execute cycle, which stops at the
+// first runtime error.
+func (vm *VM) run() (err error) {
 	var (
 		ip  int
 		ins code.Instructions
 		op  code.Opcode
 	)
 
+	// Bugs in the VM or malformed bytecode may cause Go panics, ex: an
+	// index out of range. They're turned into runtime errors, so that
+	// running a script can't bring down the program embedding the VM.
+	defer func() {
+		if r := recover(); r != nil {
+			rtErr := vm.newRuntimeError(op, ip, fmt.Errorf("internal error: %v", r))
+			rtErr.internal = true
+			err = rtErr
+		}
+	}()
+
 	// After accessing the instructions inside the vm via the current frame,
 	// increment over the instruction pointer, fetching the current
 	// instruction by accessing vm.instructions, turning the byte
@@ -97,33 +129,39 @@ func (vm *VM) Run() error {
 
 			// execute
 			if err := vm.push(vm.constants[constIndex]); err != nil { // push the const onto the stack.
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight:
 			if err := vm.executeBinaryOperation(op); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpPop:
 			vm.pop()
 		case code.OpTrue:
 			if err := vm.push(True); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpFalse:
 			if err := vm.push(False); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
-		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan:
+		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpGreaterThanOrEqual,
+			code.OpLessThan, code.OpLessThanOrEqual:
 			if err := vm.executeComparison(op); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpBang:
 			if err := vm.executeBangOperator(); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpMinus:
 			if err := vm.executeMinusOperator(); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
+			}
+		case code.OpBitNot:
+			if err := vm.executeBitNotOperator(); err != nil {
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpJump:
 			pos := int(code.ReadUint16(ins[ip+1:])) // Decode the operand right after the opcode.
@@ -138,7 +176,7 @@ func (vm *VM) Run() error {
 			}
 		case code.OpNull:
 			if err := vm.push(Null); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpSetGlobal:
 			globalIndex := code.ReadUint16(ins[ip+1:])
@@ -150,7 +188,7 @@ func (vm *VM) Run() error {
 			vm.currentFrame().ip += 2
 
 			if err := vm.push(vm.globals[globalIndex]); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpSetLocal:
 			localIndex := code.ReadUint8(ins[ip+1:])
@@ -159,14 +197,30 @@ func (vm *VM) Run() error {
 			frame := vm.currentFrame()
 
 			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+		case code.OpAssignLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			frame := vm.currentFrame()
+
+			store(&vm.stack[frame.basePointer+int(localIndex)], vm.pop())
 		case code.OpGetLocal:
 			localIndex := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip++
 
 			frame := vm.currentFrame()
 
-			if err := vm.push(vm.stack[frame.basePointer+int(localIndex)]); err != nil {
-				return err
+			if err := vm.push(load(vm.stack[frame.basePointer+int(localIndex)])); err != nil {
+				return vm.newRuntimeError(op, ip, err)
+			}
+		case code.OpCaptureLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip++
+
+			frame := vm.currentFrame()
+
+			if err := vm.push(capture(&vm.stack[frame.basePointer+int(localIndex)])); err != nil {
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpArray:
 			numElements := int(code.ReadUint16(ins[ip+1:]))
@@ -176,7 +230,7 @@ func (vm *VM) Run() error {
 			vm.sp -= numElements
 
 			if err := vm.push(array); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpHash:
 			numElements := int(code.ReadUint16(ins[ip+1:]))
@@ -184,27 +238,110 @@ func (vm *VM) Run() error {
 
 			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
 			if err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 			vm.sp -= numElements
 
 			if err := vm.push(hash); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpIndex:
 			index := vm.pop()
 			left := vm.pop()
 
 			if err := vm.executeIndexExpression(left, index); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
+			}
+		case code.OpSetIndex:
+			value := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+
+			if err := vm.executeSetIndex(left, index, value); err != nil {
+				return vm.newRuntimeError(op, ip, err)
+			}
+		case code.OpUpdateIndex:
+			binaryOp := code.Opcode(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+
+			value := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+
+			if err := vm.executeUpdateIndex(binaryOp, left, index, value); err != nil {
+				return vm.newRuntimeError(op, ip, err)
+			}
+		case code.OpUnpackArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			withRest := code.ReadUint8(ins[ip+3:]) == 1
+			vm.currentFrame().ip += 3
+
+			values, err := object.DestructureArray(vm.pop(), numElements, withRest)
+			if err != nil {
+				return vm.newRuntimeError(op, ip, err)
+			}
+			for _, v := range values {
+				if err := vm.push(v); err != nil {
+					return vm.newRuntimeError(op, ip, err)
+				}
+			}
+		case code.OpUnpackHash:
+			numKeys := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			keys := make([]string, numKeys)
+			for i, key := range vm.stack[vm.sp-numKeys : vm.sp] {
+				keys[i] = key.(*object.String).Value
+			}
+			hash := vm.stack[vm.sp-numKeys-1]
+			vm.sp = vm.sp - numKeys - 1
+
+			values, err := object.DestructureHash(hash, keys)
+			if err != nil {
+				return vm.newRuntimeError(op, ip, err)
+			}
+			for _, v := range values {
+				if err := vm.push(v); err != nil {
+					return vm.newRuntimeError(op, ip, err)
+				}
 			}
 		case code.OpCall:
 			numArgs := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1
 
 			if err := vm.executeCall(int(numArgs)); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
+		case code.OpCallSpread:
+			numArrays := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			numArgs, err := vm.spreadArguments(int(numArrays))
+			if err != nil {
+				return vm.newRuntimeError(op, ip, err)
+			}
+			if err := vm.executeCall(numArgs); err != nil {
+				return vm.newRuntimeError(op, ip, err)
+			}
+		case code.OpJumpIfArg:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			param := int(code.ReadUint8(ins[ip+3:]))
+			vm.currentFrame().ip += 3
+
+			if param < vm.currentFrame().numArgs {
+				vm.currentFrame().ip = pos - 1
+			}
+		case code.OpTry:
+			handlerIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			frame := vm.currentFrame()
+			frame.handlers = append(frame.handlers, handler{Handler: frame.cl.Fn.Handlers[handlerIndex], sp: vm.sp})
+		case code.OpEndTry:
+			frame := vm.currentFrame()
+			frame.handlers = frame.handlers[:len(frame.handlers)-1]
+		case code.OpThrow:
+			return vm.throw(op, ip, vm.pop())
 		case code.OpReturnValue:
 			returnValue := vm.pop()
 
@@ -212,14 +349,14 @@ func (vm *VM) Run() error {
 			vm.sp = frame.basePointer - 1
 
 			if err := vm.push(returnValue); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpReturn:
 			frame := vm.popFrame()
 			vm.sp = frame.basePointer - 1
 
 			if err := vm.push(Null); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpGetBuiltin:
 			builtinIndex := code.ReadUint8(ins[ip+1:])
@@ -228,7 +365,7 @@ func (vm *VM) Run() error {
 			definition := object.Builtins[builtinIndex]
 
 			if err := vm.push(definition.Builtin); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpClosure:
 			constIndex := code.ReadUint16(ins[ip+1:])
@@ -236,7 +373,15 @@ func (vm *VM) Run() error {
 			vm.currentFrame().ip += 3
 
 			if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
+			}
+		case code.OpQuote:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numUnquoted := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			if err := vm.pushQuote(int(constIndex), int(numUnquoted)); err != nil {
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpGetFree:
 			freeIndex := code.ReadUint8(ins[ip+1:])
@@ -244,15 +389,51 @@ func (vm *VM) Run() error {
 
 			currentClosure := vm.currentFrame().cl
 
+			if err := vm.push(load(currentClosure.Free[freeIndex])); err != nil {
+				return vm.newRuntimeError(op, ip, err)
+			}
+		case code.OpSetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+
+			store(&currentClosure.Free[freeIndex], vm.pop())
+		case code.OpCaptureFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+
+			// Free variables are passed on as they are, so nested
+			// closures share the cells of the variables they capture.
 			if err := vm.push(currentClosure.Free[freeIndex]); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
 		case code.OpCurrentClosure:
 			currentClosure := vm.currentFrame().cl
 
 			if err := vm.push(currentClosure); err != nil {
-				return err
+				return vm.newRuntimeError(op, ip, err)
 			}
+		case code.OpIter:
+			if err := vm.executeIter(); err != nil {
+				return vm.newRuntimeError(op, ip, err)
+			}
+		case code.OpIterNext:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			withKey := code.ReadUint8(ins[ip+3:]) == 1
+			vm.currentFrame().ip += 3
+
+			done, err := vm.executeIterNext(withKey)
+			if err != nil {
+				return vm.newRuntimeError(op, ip, err)
+			}
+			if done {
+				vm.currentFrame().ip = pos - 1
+			}
+		default:
+			return vm.newRuntimeError(op, ip, fmt.Errorf("unknown opcode: %d", op))
 		}
 	}
 
@@ -307,6 +488,8 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	switch {
 	case leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ:
 		return vm.executeBinaryIntegerOperation(op, left, right)
+	case isNumber(left) && isNumber(right):
+		return vm.executeBinaryFloatOperation(op, left, right)
 	case leftType == object.STRING_OBJ && rightType == object.STRING_OBJ:
 		return vm.executeBinaryStringOperation(op, left, right)
 	default:
@@ -328,7 +511,36 @@ func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.O
 	case code.OpMul:
 		result = leftValue * rightValue
 	case code.OpDiv:
+		if rightValue == 0 {
+			return fmt.Errorf("division by zero")
+		}
 		result = leftValue / rightValue
+	case code.OpMod:
+		if rightValue == 0 {
+			return fmt.Errorf("modulo by zero")
+		}
+		result = leftValue % rightValue
+	case code.OpPow:
+		// A negative exponent yields a fraction.
+		if rightValue < 0 {
+			return vm.push(&object.Float{Value: math.Pow(float64(leftValue), float64(rightValue))})
+		}
+		result = integerPower(leftValue, rightValue)
+	case code.OpBitAnd:
+		result = leftValue & rightValue
+	case code.OpBitOr:
+		result = leftValue | rightValue
+	case code.OpBitXor:
+		result = leftValue ^ rightValue
+	case code.OpShiftLeft, code.OpShiftRight:
+		if rightValue < 0 {
+			return fmt.Errorf("negative shift count: %d", rightValue)
+		}
+		if op == code.OpShiftLeft {
+			result = leftValue << rightValue
+		} else {
+			result = leftValue >> rightValue
+		}
 	default:
 		return fmt.Errorf("unknown integer operator: %d", op)
 	}
@@ -336,6 +548,50 @@ func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.O
 	return vm.push(&object.Integer{Value: result})
 }
 
+// integerPower raises base to the non-negative exponent exp by
+// repeated squaring. Like the other integer operations, the
+// result wraps around on overflow.
+func integerPower(base, exp int64) int64 {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+
+	return result
+}
+
+// executeBinaryFloatOperation handles arithmetic on two numbers of which
+// at least one is a float, converting any integer operand to a float.
+func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left, right object.Object) error {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	var result float64
+
+	switch op {
+	case code.OpAdd:
+		result = leftValue + rightValue
+	case code.OpSub:
+		result = leftValue - rightValue
+	case code.OpMul:
+		result = leftValue * rightValue
+	case code.OpDiv:
+		result = leftValue / rightValue
+	case code.OpMod:
+		result = math.Mod(leftValue, rightValue)
+	case code.OpPow:
+		result = math.Pow(leftValue, rightValue)
+	default:
+		return operatorError(op, left, right)
+	}
+
+	return vm.push(&object.Float{Value: result})
+}
+
 func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
 	if op != code.OpAdd {
 		return fmt.Errorf("unknown string operator: %d", op)
@@ -359,13 +615,17 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 		return vm.executeIntegerComparison(op, left, right)
 	}
 
+	if isNumber(left) && isNumber(right) {
+		return vm.executeFloatComparison(op, left, right)
+	}
+
 	switch op {
 	case code.OpEqual:
 		return vm.push(nativeBoolToBooleanObject(right == left))
 	case code.OpNotEqual:
 		return vm.push(nativeBoolToBooleanObject(right != left))
 	default:
-		return fmt.Errorf("unknown operator: %d (%s %s)", op, left.Type(), right.Type())
+		return operatorError(op, left, right)
 	}
 }
 
@@ -382,11 +642,89 @@ func (vm *VM) executeIntegerComparison(op code.Opcode, left, right object.Object
 		return vm.push(nativeBoolToBooleanObject(rightValue != leftValue))
 	case code.OpGreaterThan:
 		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue <= rightValue))
 	default:
-		return fmt.Errorf("unknown operator: %d", op)
+		return operatorError(op, left, right)
 	}
 }
 
+// executeFloatComparison compares two numbers of which at least
+// one is a float, converting any integer operand to a float.
+func (vm *VM) executeFloatComparison(op code.Opcode, left, right object.Object) error {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(rightValue == leftValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(rightValue != leftValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue <= rightValue))
+	default:
+		return operatorError(op, left, right)
+	}
+}
+
+// infixOperators maps the opcodes of infix operators to the
+// operators they're compiled from, for use in error messages.
+var infixOperators = map[code.Opcode]string{
+	code.OpAdd:                "+",
+	code.OpSub:                "-",
+	code.OpMul:                "*",
+	code.OpDiv:                "/",
+	code.OpMod:                "%",
+	code.OpPow:                "**",
+	code.OpBitAnd:             "&",
+	code.OpBitOr:              "|",
+	code.OpBitXor:             "^",
+	code.OpShiftLeft:          "<<",
+	code.OpShiftRight:         ">>",
+	code.OpEqual:              "==",
+	code.OpNotEqual:           "!=",
+	code.OpGreaterThan:        ">",
+	code.OpGreaterThanOrEqual: ">=",
+	code.OpLessThan:           "<",
+	code.OpLessThanOrEqual:    "<=",
+}
+
+// operatorError reports that the operator op doesn't apply to left
+// and right, in the same terms as the evaluator: a type mismatch for
+// operands of different types, other than two numbers, and an unknown
+// operator otherwise.
+func operatorError(op code.Opcode, left, right object.Object) error {
+	if left.Type() != right.Type() && !(isNumber(left) && isNumber(right)) {
+		return fmt.Errorf("type mismatch: %s %s %s", left.Type(), infixOperators[op], right.Type())
+	}
+
+	return fmt.Errorf("unknown operator: %s %s %s", left.Type(), infixOperators[op], right.Type())
+}
+
+// isNumber checks whether obj is an integer or a float.
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// toFloat returns the value of an integer or float object as a float64.
+func toFloat(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
+	}
+
+	return obj.(*object.Float).Value
+}
+
 // nativeBoolToBooleanObject turns the inputted boolean into a True or False.
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
@@ -414,23 +752,75 @@ func (vm *VM) executeBangOperator() error {
 }
 
 // executeMinusOperator pops the operand off the stack and
-// negates the value of an integer.
+// negates the value of an integer or float.
 func (vm *VM) executeMinusOperator() error {
 	operand := vm.pop()
 
-	if operand.Type() != object.INTEGER_OBJ {
+	switch operand := operand.(type) {
+	case *object.Integer:
+		return vm.push(&object.Integer{Value: -operand.Value}) // note the negation.
+	case *object.Float:
+		return vm.push(&object.Float{Value: -operand.Value})
+	default:
 		return fmt.Errorf("unsopported type for negation: %s", operand.Type())
 	}
+}
+
+// executeBitNotOperator pops the operand off the
+// stack and flips the bits of an integer.
+func (vm *VM) executeBitNotOperator() error {
+	operand := vm.pop()
+
+	integer, ok := operand.(*object.Integer)
+	if !ok {
+		return fmt.Errorf("unsupported type for bitwise complement: %s", operand.Type())
+	}
+
+	return vm.push(&object.Integer{Value: ^integer.Value})
+}
 
-	value := operand.(*object.Integer).Value
+// executeIter replaces the iterable on top of the stack with an iterator.
+func (vm *VM) executeIter() error {
+	operand := vm.pop()
+
+	iterable, ok := operand.(object.Iterable)
+	if !ok {
+		return fmt.Errorf("cannot iterate over %s", operand.Type())
+	}
 
-	return vm.push(&object.Integer{Value: -value}) // note the negation.
+	return vm.push(iterable.Iter())
+}
+
+// executeIterNext pushes the next element of the iterator on top of
+// the stack, and its key if asked for. Once the iterator is done, it's
+// popped instead.
+func (vm *VM) executeIterNext(withKey bool) (done bool, err error) {
+	iter, ok := vm.StackTop().(object.Iterator)
+	if !ok {
+		return false, fmt.Errorf("not an iterator: %s", vm.StackTop().Type())
+	}
+
+	key, value, ok := iter.Next()
+	if !ok {
+		vm.pop()
+		return true, nil
+	}
+
+	if withKey {
+		if err := vm.push(key); err != nil {
+			return false, err
+		}
+	}
+
+	return false, vm.push(value)
 }
 
 func (vm *VM) executeIndexExpression(left, index object.Object) error {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return vm.executeArrayIndex(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeStringIndex(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return vm.executeHashIndex(left, index)
 	default:
@@ -450,6 +840,20 @@ func (vm *VM) executeArrayIndex(array, index object.Object) error {
 	return vm.push(arrayObject.Elements[i])
 }
 
+// executeStringIndex pushes the character at the given
+// rune index as a single-character string.
+func (vm *VM) executeStringIndex(str, index object.Object) error {
+	runes := str.(*object.String).Runes()
+	i := index.(*object.Integer).Value
+	max := int64(len(runes) - 1)
+
+	if i < 0 || i > max {
+		return vm.push(Null)
+	}
+
+	return vm.push(&object.String{Value: string(runes[i])})
+}
+
 func (vm *VM) executeHashIndex(hash, index object.Object) error {
 	hashObject := hash.(*object.Hash)
 
@@ -466,6 +870,70 @@ func (vm *VM) executeHashIndex(hash, index object.Object) error {
 	return vm.push(pair.Value)
 }
 
+// checkSetIndex reports why the element of left at index can't
+// be assigned to, if it can't. Unlike reading an element, which
+// results in null if it doesn't exist, assigning to an array
+// element outside of the array's bounds is an error.
+func checkSetIndex(left, index object.Object) error {
+	switch left := left.(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		if !ok {
+			return fmt.Errorf("array index must be INTEGER, got %s", index.Type())
+		}
+		if i.Value < 0 || i.Value >= int64(len(left.Elements)) {
+			return fmt.Errorf("index out of range: %d with length %d", i.Value, len(left.Elements))
+		}
+	case *object.Hash:
+		if _, ok := index.(object.Hashable); !ok {
+			return fmt.Errorf("unusable as hash key: %s", index.Type())
+		}
+	default:
+		return fmt.Errorf("index assignment not supported: %s", left.Type())
+	}
+
+	return nil
+}
+
+// executeSetIndex assigns value to the element of left at index,
+// in place, and pushes the value.
+func (vm *VM) executeSetIndex(left, index, value object.Object) error {
+	if err := checkSetIndex(left, index); err != nil {
+		return err
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		left.Elements[index.(*object.Integer).Value] = value
+	case *object.Hash:
+		left.Pairs[index.(object.Hashable).HashKey()] = object.HashPair{Key: index, Value: value}
+	}
+
+	return vm.push(value)
+}
+
+// executeUpdateIndex applies the binary operator op to the element of
+// left at index and value, assigning the result back to the element.
+func (vm *VM) executeUpdateIndex(op code.Opcode, left, index, value object.Object) error {
+	if err := checkSetIndex(left, index); err != nil {
+		return err
+	}
+
+	// Leave the current value and the operand
+	// on the stack for the binary operation.
+	if err := vm.executeIndexExpression(left, index); err != nil {
+		return err
+	}
+	if err := vm.push(value); err != nil {
+		return err
+	}
+	if err := vm.executeBinaryOperation(op); err != nil {
+		return err
+	}
+
+	return vm.executeSetIndex(left, index, vm.pop())
+}
+
 func (vm *VM) executeCall(numArgs int) error {
 	callee := vm.stack[vm.sp-1-numArgs]
 	switch callee := callee.(type) {
@@ -535,24 +1003,77 @@ func (vm *VM) popFrame() *Frame {
 }
 
 func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
-	if numArgs != cl.Fn.NumParameters {
-		return fmt.Errorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	fn := cl.Fn
+	if err := object.CheckArity(numArgs, fn.NumParameters, fn.NumDefaults, fn.Variadic); err != nil {
+		return err
+	}
+
+	if vm.framesIndex >= MaxFrames {
+		return fmt.Errorf("call stack overflow: exceeded %d nested calls", MaxFrames)
+	}
+
+	if vm.sp-numArgs+cl.Fn.NumLocals >= StackSize {
+		return fmt.Errorf("stack overflow")
 	}
 
 	frame := NewFrame(cl, vm.sp-numArgs)
+	frame.numArgs = numArgs
 	vm.pushFrame(frame)
 
+	// The arguments beyond the named parameters are packed into
+	// an array for the rest parameter. Parameters without an
+	// argument get their default value from the func itself.
+	if fn.Variadic {
+		rest := []object.Object{}
+		if numArgs > fn.NumParameters {
+			rest = make([]object.Object, numArgs-fn.NumParameters)
+			copy(rest, vm.stack[frame.basePointer+fn.NumParameters:vm.sp])
+		}
+		vm.stack[frame.basePointer+fn.NumParameters] = &object.Array{Elements: rest}
+	}
+
 	vm.sp = frame.basePointer + cl.Fn.NumLocals
 
 	return nil
 }
 
+// spreadArguments replaces the arrays on top of the stack
+// with their elements, returning how many there are.
+func (vm *VM) spreadArguments(numArrays int) (int, error) {
+	arrays := make([]object.Object, numArrays)
+	copy(arrays, vm.stack[vm.sp-numArrays:vm.sp])
+	vm.sp -= numArrays
+
+	numArgs := 0
+	for _, arr := range arrays {
+		array, ok := arr.(*object.Array)
+		if !ok {
+			return 0, fmt.Errorf("cannot spread %s", arr.Type())
+		}
+
+		for _, el := range array.Elements {
+			if err := vm.push(el); err != nil {
+				return 0, err
+			}
+		}
+		numArgs += len(array.Elements)
+	}
+
+	return numArgs, nil
+}
+
 func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
 	args := vm.stack[vm.sp-numArgs : vm.sp]
 
 	result := builtin.Fn(args...)
 	vm.sp = vm.sp - numArgs - 1
 
+	// A builtin fails by returning an error, which is raised
+	// like any other runtime error, rather than pushed.
+	if err, ok := result.(*object.Error); ok {
+		return fmt.Errorf("%s", err.Message)
+	}
+
 	if result != nil {
 		if err := vm.push(result); err != nil {
 			return err
@@ -583,3 +1104,35 @@ func (vm *VM) pushClosure(constIndex, numFree int) error {
 
 	return vm.push(closure)
 }
+
+// pushQuote pushes a copy of the quote constant at constIndex, in which
+// the calls to unquote() are replaced, in order, by the AST of the
+// numUnquoted values on top of the stack.
+func (vm *VM) pushQuote(constIndex, numUnquoted int) error {
+	quote, ok := vm.constants[constIndex].(*object.Quote)
+	if !ok {
+		return fmt.Errorf("not a quote: %+v", vm.constants[constIndex])
+	}
+
+	nodes := make([]ast.Node, numUnquoted)
+	for i := range nodes {
+		unquoted := vm.stack[vm.sp-numUnquoted+i]
+		node, ok := object.ASTNode(unquoted)
+		if !ok {
+			return fmt.Errorf("cannot unquote %s", unquoted.Type())
+		}
+		nodes[i] = node
+	}
+	vm.sp = vm.sp - numUnquoted
+
+	node := ast.Modify(quote.Node, func(node ast.Node) ast.Node {
+		if !ast.IsCallOf(node, "unquote") {
+			return node
+		}
+		next := nodes[0]
+		nodes = nodes[1:]
+		return next
+	})
+
+	return vm.push(&object.Quote{Node: node})
+}
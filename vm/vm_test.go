@@ -1,10 +1,12 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/adamwoolhether/monkeyLang/ast"
+	"github.com/adamwoolhether/monkeyLang/code"
 	"github.com/adamwoolhether/monkeyLang/compiler"
 	"github.com/adamwoolhether/monkeyLang/lexer"
 	"github.com/adamwoolhether/monkeyLang/object"
@@ -30,6 +32,73 @@ func TestIntegerArithmetic(t *testing.T) {
 		{"-10", -10},
 		{"-50 + 100 + -50", 0},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"0xFF + 0o10 + 0b1 + 1_000", 1264},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"3 ** 0", 1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 + 2 << 3", 24},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestArithmeticErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"5 / 0", "division by zero"},
+		{"5 % (2 - 2)", "modulo by zero"},
+		{"1 << -1", "negative shift count: -1"},
+		{"~true", "unsupported type for bitwise complement: BOOLEAN"},
+		{"let x = 1; x /= 0", "division by zero"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"1 | 2.0", "unknown operator: INTEGER | FLOAT"},
+		{"1.5 ^ 1.5", "unknown operator: FLOAT ^ FLOAT"},
+		{"1.0 << 2", "unknown operator: FLOAT << INTEGER"},
+		{"8 >> 1.0", "unknown operator: INTEGER >> FLOAT"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestComparisonErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`1.5 <= "a"`, "type mismatch: FLOAT <= STRING"},
+		{`1 < "a"`, "type mismatch: INTEGER < STRING"},
+		{`"a" > 1`, "type mismatch: STRING > INTEGER"},
+		{`"a" >= "b"`, "unknown operator: STRING >= STRING"},
+		{"true < false", "unknown operator: BOOLEAN < BOOLEAN"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5", 1.5},
+		{"1e3", 1000.0},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2.0},
+		{"7 / 2.0", 3.5},
+		{"10 - 2.5 * 2", 5.0},
+		{"1.5 < 2", true},
+		{"2 > 2.5", false},
+		{"1 == 1.0", true},
+		{"1.5 != 1.5", false},
+		{"7.5 % 2", 1.5},
+		{"2.0 ** 3", 8.0},
+		{"4 ** 0.5", 2.0},
+		{"2 ** -1", 0.5},
+		{"1 / 0.0 > 1e308", true},
 	}
 
 	runVmTests(t, tests)
@@ -63,6 +132,37 @@ func TestBooleanExpressions(t *testing.T) {
 		{"!!false", false},
 		{"!!5", true},
 		{"!(if (false) { 5; })", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"2.5 >= 2", true},
+		{"1 <= 0.5", false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLogicalExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"true || false", true},
+		{"1 && \"a\"", true},
+		{"0 || false", true},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"false && true || true", true},
+		{"false && (true || true)", false},
+		// The right operand is only evaluated when needed, so the
+		// errors it would otherwise raise never happen.
+		{"false && 1()", false},
+		{"true || 1()", true},
+		{"let x = 0; let check = fn(v) { v > 0 && 10 / v > 1 }; check(x)", false},
 	}
 
 	runVmTests(t, tests)
@@ -80,21 +180,253 @@ func TestConditionals(t *testing.T) {
 		{"if (1 > 2) { 10 }", Null},
 		{"if (false) { 10 }", Null},
 		{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+		{"if (true) { }", Null},
+		{"let x = if (true) { let a = 1; }; x", Null},
+		{"fn() { let a = 1; }()", Null},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestWhileLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"while (false) { 10 } 5", 5},
+		{"while (true) { break } 5", 5},
+		{"while (true) { if (false) { continue } break } 5", 5},
+		{"let f = fn() { while (true) { return 7 } }; f()", 7},
+		{"let f = fn() { while (true) { while (true) { break } return 3 } }; f()", 3},
+		{"let f = fn(x) { while (true) { if (x) { break } return 1 } 2 }; [f(true), f(false)]", []int{2, 1}},
+		{"let f = fn() { while (false) { } }; f()", Null},
+		{"if (true) { while (false) { } }", Null},
+		{`
+let i = 0;
+while (i < 5000) {
+	i = i + 1;
+	if (i > 2) { if (true) { continue } } else { try { continue } finally { } }
+	let z = [1, 2];
+}
+i`, 5000},
 	}
 
 	runVmTests(t, tests)
 }
 
+func TestForInLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"for (x in []) { x } 5", 5},
+		{"for (x in [1, 2]) { break } 5", 5},
+		{"for (x in [1, 2]) { for (y in [3, 4]) { break } continue } 5", 5},
+		{"let f = fn(xs) { for (x in xs) { if (x > 2) { return x } } -1 }; [f([1, 2, 3, 4]), f([])]", []int{3, -1}},
+		{"let f = fn(s) { for (i, c in s) { if (i == 1) { return c } } }; f(\"héllo\")", "é"},
+		{"let f = fn(h) { for (k, v in h) { if (v > 1) { return k } } }; f({\"a\": 1, \"b\": 2})", "b"},
+		{"let f = fn() { for (i in range(10, 0, -3)) { if (i < 5) { return i } } }; f()", 4},
+		{"let f = fn() { for (i, x in range(5, 10)) { if (x == 7) { return i } } }; f()", 2},
+		{"let f = fn() { for (x in [1]) { } }; f()", Null},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return fn() { x } } } }; f()()", 2},
+		{"let s = []; for (x in [1, 2, 3]) { if (x == 2) { continue } s = push(s, 10 + x) } s", []int{11, 13}},
+		{"let n = 0; for (x in range(5000)) { n = n + 1; if (x > 0) { if (true) { continue } } let z = [x] } n", 5000},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestForInErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"for (x in 5) { }", "cannot iterate over INTEGER"},
+		{"for (x in fn() { }) { }", "cannot iterate over CLOSURE"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
 func TestGlobalLetStatements(t *testing.T) {
 	tests := []vmTestCase{
 		{"let one = 1; one", 1},
 		{"let one = 1; let two = 2; one + two", 3},
 		{"let one = 1; let two = one + one; one + two", 3},
+		{"const one = 1; one", 1},
+		{"const one = 1; if (true) { let one = 1; one = 2; } one", 1},
+		{"const one = 1; let f = fn() { let one = 2; one }; f() + one", 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []vmTestCase{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x += 2", 3},
+		{"let x = 10; x -= 4; x *= 3; x /= 2", 9},
+		{"let a = 1; let b = a = 5; [a, b]", []int{5, 5}},
+		{"let x = 1; let f = fn() { x = 2 }; f(); x", 2},
+		{"let f = fn() { let x = 1; x += 1; x }; f()", 2},
+		{"let f = fn(n) { n *= 2; n }; f(4)", 8},
+		{"let f = fn(f) { f = 1; f }; f(2)", 1},
+		{"let f = fn() { let f = 2; f += 1 }; f()", 3},
+		{"let i = 0; while (i < 5000) { i += 1 } i", 5000},
+		{"let sum = 0; for (x in range(101)) { sum += x } sum", 5050},
 	}
 
 	runVmTests(t, tests)
 }
 
+func TestAssigningCapturedVariables(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+			let counter = fn() { let c = 0; fn() { c += 1 } };
+			let next = counter();
+			next(); next(); next();
+			`,
+			expected: 3,
+		},
+		{
+			// The function defining a variable sees a closure's assignment.
+			input: `
+			let f = fn() { let x = 1; let set = fn(v) { x = v }; set(7); x };
+			f();
+			`,
+			expected: 7,
+		},
+		{
+			// Closures capturing the same variable share it.
+			input: `
+			let make = fn() { let n = 0; [fn() { n += 1 }, fn() { n }] };
+			let fs = make();
+			fs[0](); fs[0](); fs[1]();
+			`,
+			expected: 2,
+		},
+		{
+			input: `
+			let f = fn() {
+				let x = 0;
+				let g = fn() { let h = fn() { x += 10 }; h() };
+				g(); g();
+				x
+			};
+			f();
+			`,
+			expected: 20,
+		},
+		{
+			// Each call creates variables of its own.
+			input: `
+			let counter = fn() { let c = 0; fn() { c += 1 } };
+			let a = counter();
+			let b = counter();
+			a(); a(); b();
+			`,
+			expected: 1,
+		},
+		{
+			// A later call reusing the stack slot of a captured
+			// variable mustn't assign to it.
+			input: `
+			let f = fn() { let x = 1; fn() { x } };
+			let g = f();
+			let h = fn() { let y = 5; y };
+			h();
+			g();
+			`,
+			expected: 1,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []vmTestCase{
+		{"let x = 1; if (true) { let x = 2; } x", 1},
+		{"let x = 1; if (true) { let x = x + 1; x }", 2},
+		{"let x = 1; if (true) { x = 2; } x", 2},
+		{"let f = fn(c) { if (c) { let a = 1; a } else { let b = 2; b } }; f(true) + f(false) * 10", 21},
+		{"let x = 1; for (x in [5]) { x } x", 1},
+		{"let fs = []; for (i in range(3)) { fs = push(fs, fn() { i }) } fs[0]() + fs[1]() * 10 + fs[2]() * 100", 210},
+		{"let fs = []; let i = 0; while (i < 3) { let j = i; fs = push(fs, fn() { j }); i += 1 } fs[0]() + fs[2]() * 10", 20},
+		{
+			// A slot reused after its block ends mustn't
+			// assign to the variable a closure captured.
+			input: `
+			let f = fn() {
+				let g = 0;
+				if (true) { let a = 1; g = fn() { a } }
+				let b = 2;
+				g() + b
+			};
+			f();
+			`,
+			expected: 3,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIndexAssignments(t *testing.T) {
+	tests := []vmTestCase{
+		{"let a = [1, 2, 3]; a[1] = 5; a", []int{1, 5, 3}},
+		{"let a = [1, 2, 3]; a[2] += 10", 13},
+		{"let a = [[1], [2]]; a[1][0] *= 7; a[1]", []int{14}},
+		{`let h = {"a": 1}; h["a"] = 2; h["b"] = 3; [h["a"], h["b"]]`, []int{2, 3}},
+		{`let h = {"n": 5}; h["n"] -= 1; h["n"]`, 4},
+		{"let a = [1]; let b = a; b[0] = 9; a[0]", 9},
+		{"let f = fn(xs) { xs[0] = 4 }; let a = [0]; f(a); a", []int{4}},
+		{`
+		let n = 100;
+		let xs = [];
+		for (i in range(n)) { xs = push(xs, 0) }
+		for (i in range(1, n)) { xs[i] = xs[i - 1] + i }
+		xs[n - 1]
+		`, 4950},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIndexAssignmentErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2][2] = 0", "index out of range: 2 with length 2"},
+		{"[1, 2][-1] = 0", "index out of range: -1 with length 2"},
+		{`[1, 2]["0"] = 0`, "array index must be INTEGER, got STRING"},
+		{"{}[fn() {}] = 1", "unusable as hash key: CLOSURE"},
+		{`"abc"[0] = "x"`, "index assignment not supported: STRING"},
+		{`{}["a"] += 1`, "unsupported types for binary operation: NULL INTEGER"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []vmTestCase{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, ...b] = [1, 2, 3]; a + len(b) * 10 + b[1] * 100", 321},
+		{"let [a, ...b] = [1]; len(b)", 0},
+		{"let xs = [1, 2]; let [...ys] = xs; ys[0] = 5; xs[0]", 1},
+		{`let {name, age} = {"name": 1, "age": 2, "other": 3}; name * 10 + age`, 12},
+		{"let f = fn(p) { let [x, y] = p; x - y }; f([5, 3])", 2},
+		{"let x = 1; if (true) { let [x] = [x + 1]; x }", 2},
+		{"let x = 1; if (true) { let [x] = [2]; } x", 1},
+		{"let fs = []; for (p in [[1, 2], [3, 4]]) { let [a, b] = p; fs = push(fs, fn() { a + b }) } fs[0]() * 10 + fs[1]()", 37},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"let [a, b] = [1]", "wrong number of elements to destructure: want=2, got=1"},
+		{"let [a] = [1, 2]", "wrong number of elements to destructure: want=1, got=2"},
+		{"let [a, b, ...c] = [1]", "wrong number of elements to destructure: want=2 or more, got=1"},
+		{"let [a] = {}", "cannot destructure HASH as an array"},
+		{`let {a} = {"b": 1}`, `hash has no key "a"`},
+		{"let {a} = [1]", "cannot destructure ARRAY as a hash"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
 func TestStringExpressions(t *testing.T) {
 	tests := []vmTestCase{
 		{`"monkey"`, "monkey"},
@@ -151,6 +483,12 @@ func TestIndexExpressions(t *testing.T) {
 		{"{1: 1, 2: 2}[2]", 2},
 		{"{1: 1}[0]", Null},
 		{"{}[0]", Null},
+		{`"monkey"[0]`, "m"},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[2]`, "l"},
+		{`let größe = "日本語"; größe[len(größe) - 1]`, "語"},
+		{`"monkey"[6]`, Null},
+		{`"monkey"[-1]`, Null},
 	}
 
 	runVmTests(t, tests)
@@ -378,6 +716,29 @@ func TestCallingFunctionsWithArgumentsAndBindings(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []vmTestCase{
+		{"let f = fn(a, b = 2) { a + b }; f(1)", 3},
+		{"let f = fn(a, b = 2) { a + b }; f(1, 5)", 6},
+		{"let f = fn(a, b = a * 10) { b }; f(3)", 30},
+		{"let x = 100; let f = fn(a = x) { a }; f()", 100},
+		{"let f = fn(a, ...r) { len(r) }; f(1)", 0},
+		{"let f = fn(a, ...r) { r[1] }; f(1, 2, 3)", 3},
+		{"let f = fn(a = 5, ...r) { a + len(r) }; f()", 5},
+		{"let f = fn(a = if (true) { let t = 1; let u = 2; t + u }, ...r) { a + len(r) }; f()", 3},
+		{"let f = fn(a, g = fn() { a }) { a = 2; g() }; f(1)", 2},
+		{"let a = 1; let f = fn(a = fn() { a }) { a }; f()()", 1},
+		{"let f = fn(a, b = fn() { a += 1 }) { b(); a }; f(1)", 2},
+		{"let f = fn(a = 1, b = fn() { a }) { a = 5; b() }; f()", 5},
+		{"let sum = fn(n, acc = 0) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(4)", 10},
+		{"let f = fn(...r) { len(r) }; f(...[1, 2], 3, ...[])", 3},
+		{"let f = fn(a, b, c) { a * 100 + b * 10 + c }; f(...[1, 2], 3)", 123},
+		{`len(...["abc"])`, 3},
+	}
+
+	runVmTests(t, tests)
+}
+
 func TestCallingFunctionsWithWrongArguments(t *testing.T) {
 	tests := []vmTestCase{
 		{
@@ -392,6 +753,26 @@ func TestCallingFunctionsWithWrongArguments(t *testing.T) {
 			input:    `fn(a, b) { a + b; }(1);`,
 			expected: `wrong number of arguments: want=2, got=1`,
 		},
+		{
+			input:    `fn(a, b = 2) { a }()`,
+			expected: `wrong number of arguments: want=1..2, got=0`,
+		},
+		{
+			input:    `fn(a, b = 2) { a }(1, 2, 3)`,
+			expected: `wrong number of arguments: want=1..2, got=3`,
+		},
+		{
+			input:    `fn(a, ...r) { a }()`,
+			expected: `wrong number of arguments: want=1 or more, got=0`,
+		},
+		{
+			input:    `fn(a) { a }(...[1, 2])`,
+			expected: `wrong number of arguments: want=1, got=2`,
+		},
+		{
+			input:    `fn(a) { a }(...1)`,
+			expected: `cannot spread INTEGER`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -420,46 +801,83 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
-		{
-			`len(1)`,
-			&object.Error{
-				Message: "argument to `len` not supported, got INTEGER",
-			},
-		},
-		{`len("one", "two")`,
-			&object.Error{
-				Message: "wrong number of arguments. got=2, want=1",
-			},
-		},
+		{`len("héllo wörld")`, 11},
+		{`len("日本語")`, 3},
 		{`len([1, 2, 3])`, 3},
 		{`len([])`, 0},
 		{`puts("hello", "world!")`, Null},
 		{`first([1, 2, 3])`, 1},
 		{`first([])`, Null},
-		{`first(1)`,
-			&object.Error{
-				Message: "argument to `first` must be ARRAY, got INTEGER",
-			},
-		},
 		{`last([1, 2, 3])`, 3},
 		{`last([])`, Null},
-		{`last(1)`,
-			&object.Error{
-				Message: "argument to `last` must be ARRAY, got INTEGER",
-			},
-		},
 		{`rest([1, 2, 3])`, []int{2, 3}},
 		{`rest([])`, Null},
 		{`push([], 1)`, []int{1}},
-		{`push(1, 1)`,
-			&object.Error{
-				Message: "argument to `push` must be ARRAY, got INTEGER",
-			},
-		},
+		{`int(2.7)`, 2},
+		{`int("42")`, 42},
+		{`float(2)`, 2.0},
+		{`float("0.25")`, 0.25},
 	}
 	runVmTests(t, tests)
 }
 
+func TestBuiltinFunctionErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`first(1)`, "argument to `first` must be ARRAY, got INTEGER"},
+		{`last(1)`, "argument to `last` must be ARRAY, got INTEGER"},
+		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+		{`int([])`, "argument to `int` not supported, got ARRAY"},
+		{`int("abc")`, `cannot convert "abc" to INTEGER`},
+		{`int("4.2")`, `cannot convert "4.2" to INTEGER`},
+		{`float("x")`, `cannot convert "x" to FLOAT`},
+		{`float(true)`, "argument to `float` not supported, got BOOLEAN"},
+		{`int(1, 2)`, "wrong number of arguments. got=2, want=1"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`let foobar = 8; quote(unquote(foobar) * unquote(foobar < 1))`, `(8 * false)`},
+		{`quote(unquote(1.5 * 3) + unquote("a" + "b"))`, `(4.5 + ab)`},
+		{`quote(unquote(quote(4 + unquote(2 * 2))))`, `(4 + 4)`},
+		{`let f = fn(x) { quote(unquote(x)) }; [f(1), f(2)][1]`, `2`},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		quote, ok := vm.LastPoppedStackElem().(*object.Quote)
+		if !ok {
+			t.Fatalf("input %q: object is not Quote. got=%T", tt.input, vm.LastPoppedStackElem())
+		}
+
+		if quote.Node.String() != tt.expected {
+			t.Errorf("input %q: wrong quote. want=%q, got=%q", tt.input, tt.expected, quote.Node.String())
+		}
+	}
+
+	runVmErrorTests(t, []vmTestCase{
+		{`quote(unquote(fn() {}))`, "cannot unquote CLOSURE"},
+	})
+}
+
 func TestClosures(t *testing.T) {
 	tests := []vmTestCase{
 		{
@@ -638,6 +1056,278 @@ func runVmTests(t *testing.T, tests []vmTestCase) {
 	}
 }
 
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input      string
+		expectedOp code.Opcode
+		expectedIP int
+		expected   string
+	}{
+		{
+			input:      "1; 2 / 0",
+			expectedOp: code.OpDiv,
+			expectedIP: 10,
+			expected:   "division by zero",
+		},
+		{
+			input:      "let f = fn() { f() }; f()",
+			expectedOp: code.OpCall,
+			expectedIP: 1,
+			expected:   "call stack overflow: exceeded 1024 nested calls",
+		},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err := New(comp.Bytecode()).Run()
+
+		var rtErr *RuntimeError
+		if !errors.As(err, &rtErr) {
+			t.Fatalf("input %q: expected *RuntimeError, got=%T (%v)", tt.input, err, err)
+		}
+
+		if rtErr.Msg != tt.expected {
+			t.Errorf("input %q: wrong message. want=%q, got=%q", tt.input, tt.expected, rtErr.Msg)
+		}
+		if rtErr.Op != tt.expectedOp {
+			t.Errorf("input %q: wrong opcode. want=%d, got=%s", tt.input, tt.expectedOp, rtErr.OpName())
+		}
+		if rtErr.IP != tt.expectedIP {
+			t.Errorf("input %q: wrong ip. want=%d, got=%d", tt.input, tt.expectedIP, rtErr.IP)
+		}
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []vmTestCase{
+		{`let r = 0; try { throw 5; r = 1 } catch (e) { r = e["value"] } r`, 5},
+		{`let r = ""; try { 1 / 0 } catch (e) { r = e["message"] } r`, "division by zero"},
+		{`let r = ""; try { throw {"message": "from a hash"} } catch (e) { r = e["message"] } r`, "from a hash"},
+		{`let r = 0; try { 1 / 0 } catch (e) { r = e["value"] } r`, Null},
+		{`let r = ""; try { len(1) } catch (e) { r = e["message"] } r`, "argument to `len` not supported, got INTEGER"},
+		{`let f = fn(s) { try { return int(s) } catch (e) { return -1 } }; [f("7"), f("x")]`, []int{7, -1}},
+		{
+			`let f = fn() { throw "deep" };
+			let g = fn() { f() };
+			let r = "";
+			try { g() } catch (e) { r = e["trace"][2] }
+			r`,
+			"at <main> (4:10)",
+		},
+		{
+			// A catch block only sees the frames up to its own.
+			`let f = fn() { throw "deep" };
+			let g = fn() { try { f() } catch (e) { return e["trace"][1] } };
+			g()`,
+			"at g (2:25)",
+		},
+		{
+			`let f = fn() { f() };
+			let r = "";
+			try { f() } catch (e) { r = e["message"] }
+			r`,
+			"call stack overflow: exceeded 1024 nested calls",
+		},
+		{`let r = ""; try { try { throw "a" } catch (e) { throw e } } catch (e) { r = e["message"] } r`, "a"},
+		{`let r = ""; try { try { throw "a" } catch (e) { throw "b" } } catch (e) { r = e["message"] } r`, "b"},
+		{
+			`let r = [];
+			try {
+				try { throw 1 } finally { r = push(r, 2) }
+			} catch (e) { r = push(r, e["value"]) } finally { r = push(r, 3) }
+			r`,
+			[]int{2, 1, 3},
+		},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, 2},
+		{`let r = 0; let f = fn() { try { return 1 } finally { r = 2 } }; f() + r`, 3},
+		{`let f = fn() { try { throw "a" } catch (e) { return 1 } finally { 2 } }; f()`, 1},
+		{`let f = fn() { try { 1 } catch (e) { 2 } }; f()`, Null},
+		{
+			`let n = 0;
+			for (x in [1, 2, 3, 4]) {
+				try { if (x == 2) { continue } if (x == 4) { break } } finally { n += x }
+			}
+			n`,
+			10,
+		},
+		{
+			`let s = 0;
+			for (x in [1, 2, 3]) {
+				try { if (x == 2) { throw x } s += x } catch (e) { s += e["value"] * 10 }
+			}
+			s`,
+			24,
+		},
+		{
+			// The error being rethrown is discarded by the break.
+			`let s = 0;
+			for (x in [1, 2]) { try { throw x } finally { s += x; break } }
+			s`,
+			1,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestUncaughtExceptions(t *testing.T) {
+	tests := []vmTestCase{
+		{`throw "boom"`, "boom"},
+		{`throw 1 + 1`, "2"},
+		{`try { 1 } finally { throw "from finally" }`, "from finally"},
+		{`let f = fn() { try { throw "a" } finally { 1 } }; f()`, "a"},
+		{`try { throw "a" } catch (e) { throw "b" } finally { 1 }`, "b"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestRethrownStackTrace(t *testing.T) {
+	input := `let f = fn() {
+  try { throw "a" } finally { 1 }
+};
+f();`
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	err := New(comp.Bytecode()).Run()
+
+	var rtErr *RuntimeError
+	if !errors.As(err, &rtErr) {
+		t.Fatalf("expected *RuntimeError, got=%T (%v)", err, err)
+	}
+
+	// The trace leads to the throw, not to where the finally block rethrew it.
+	if rtErr.Trace.String() != "    at f (2:9)\n    at <main> (4:1)" {
+		t.Errorf("wrong trace. got=\n%s", rtErr.Trace)
+	}
+}
+
+func TestStackTraces(t *testing.T) {
+	input := `let inner = fn(x) {
+  x + true
+};
+let outer = fn() {
+  inner(1)
+};
+outer();`
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	err := New(comp.Bytecode()).Run()
+
+	var rtErr *RuntimeError
+	if !errors.As(err, &rtErr) {
+		t.Fatalf("expected *RuntimeError, got=%T (%v)", err, err)
+	}
+
+	expected := []struct {
+		function string
+		pos      string
+	}{
+		{"inner", "2:5"},
+		{"outer", "5:3"},
+		{"<main>", "7:1"},
+	}
+
+	if len(rtErr.Trace) != len(expected) {
+		t.Fatalf("wrong number of frames. want=%d, got=%d\n%s", len(expected), len(rtErr.Trace), rtErr.Trace)
+	}
+
+	for i, frame := range expected {
+		if rtErr.Trace[i].Function != frame.function || rtErr.Trace[i].Pos.String() != frame.pos {
+			t.Errorf("frame %d wrong. want=%s at %s, got=%s", i, frame.function, frame.pos, rtErr.Trace[i])
+		}
+	}
+}
+
+func TestMalformedBytecode(t *testing.T) {
+	tests := []struct {
+		name         string
+		instructions []code.Instructions
+		expectedOp   code.Opcode
+		expectedMsg  string
+	}{
+		{
+			name:         "missing constant",
+			instructions: []code.Instructions{code.Make(code.OpConstant, 5)},
+			expectedOp:   code.OpConstant,
+			expectedMsg:  "internal error: runtime error: index out of range [5] with length 0",
+		},
+		{
+			name:         "pop from empty stack",
+			instructions: []code.Instructions{code.Make(code.OpAdd)},
+			expectedOp:   code.OpAdd,
+			expectedMsg:  "internal error: runtime error: index out of range [-1]",
+		},
+		{
+			name:         "unknown opcode",
+			instructions: []code.Instructions{{255}},
+			expectedOp:   255,
+			expectedMsg:  "unknown opcode: 255",
+		},
+	}
+
+	for _, tt := range tests {
+		var ins code.Instructions
+		for _, i := range tt.instructions {
+			ins = append(ins, i...)
+		}
+
+		err := New(&compiler.Bytecode{Instructions: ins}).Run()
+
+		var rtErr *RuntimeError
+		if !errors.As(err, &rtErr) {
+			t.Fatalf("%s: expected *RuntimeError, got=%T (%v)", tt.name, err, err)
+		}
+
+		if rtErr.Msg != tt.expectedMsg {
+			t.Errorf("%s: wrong message. want=%q, got=%q", tt.name, tt.expectedMsg, rtErr.Msg)
+		}
+		if rtErr.Op != tt.expectedOp || rtErr.IP != 0 {
+			t.Errorf("%s: wrong location. want=%d at 0, got=%d at %d", tt.name, tt.expectedOp, rtErr.Op, rtErr.IP)
+		}
+	}
+}
+
+// runVmErrorTests runs each test's input, expecting the
+// VM to fail with the error message in tt.expected.
+func runVmErrorTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("input %q: expected VM error but resulted in none.", tt.input)
+		}
+
+		if err.Error() != tt.expected {
+			t.Fatalf("input %q: wrong VM error: want=%q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
 func parse(input string) *ast.Program {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -658,6 +1348,12 @@ func testExpectedObject(
 			t.Errorf("testIntegerObject failed: %s", err)
 		}
 
+	case float64:
+		err := testFloatObject(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject failed: %s", err)
+		}
+
 	case bool:
 		err := testBooleanObject(bool(expected), actual)
 		if err != nil {
@@ -747,6 +1443,21 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	return nil
 }
 
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got=%T (%+v)",
+			actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+	}
+
+	return nil
+}
+
 func testBooleanObject(expected bool, actual object.Object) error {
 	result, ok := actual.(*object.Boolean)
 	if !ok {